@@ -0,0 +1,229 @@
+package cache
+
+import "github.com/FallenTaters/cache/list"
+
+type arcList int
+
+const (
+	// t1 holds entries that were used once recently
+	t1 arcList = iota
+	// t2 holds entries that were used at least twice recently
+	t2
+	// b1 holds the keys of entries evicted from t1
+	b1
+	// b2 holds the keys of entries evicted from t2
+	b2
+)
+
+type arcEntry[K comparable, V any] struct {
+	keyValue[K, V]
+
+	list arcList
+}
+
+// arc is an Adaptive Replacement Cache. It keeps recently evicted keys in the ghost lists b1 and b2,
+// and uses hits on them to tune p, the target size of t1, between recency and frequency.
+type arc[K comparable, V any] struct {
+	evictor[K, V]
+
+	maxEntries int
+	p          int
+
+	lists   [4]*list.List[arcEntry[K, V]]
+	entries map[K]*list.Element[arcEntry[K, V]]
+}
+
+func ARC[K comparable, V any](maxEntries int, opts ...Option[K, V]) Cache[K, V] {
+	a := &arc[K, V]{
+		maxEntries: maxEntries,
+		entries:    make(map[K]*list.Element[arcEntry[K, V]], 2*maxEntries),
+	}
+	for i := range a.lists {
+		a.lists[i] = list.New[arcEntry[K, V]]()
+	}
+
+	return newBase[K, V](a, opts)
+}
+
+func (a *arc[K, V]) get(key K) (V, bool) {
+	e, ok := a.entries[key]
+	if !ok || !e.Value.resident() {
+		var empty V
+		return empty, false
+	}
+
+	a.move(e, t2)
+
+	return e.Value.value, true
+}
+
+func (a *arc[K, V]) peek(key K) (V, bool) {
+	e, ok := a.entries[key]
+	if !ok || !e.Value.resident() {
+		var empty V
+		return empty, false
+	}
+
+	return e.Value.value, true
+}
+
+func (a *arc[K, V]) readOnlyGet() bool {
+	return false
+}
+
+func (a *arc[K, V]) add(key K, value V) {
+	// without room, there are no entries or ghosts either
+	if a.maxEntries <= 0 {
+		a.evicted(key, value, ReasonCapacity)
+		return
+	}
+
+	e, ok := a.entries[key]
+	switch {
+	case ok && e.Value.resident():
+		a.evicted(key, e.Value.value, ReasonReplaced)
+		e.Value.value = value
+		a.move(e, t2)
+		return
+
+	case ok && e.Value.list == b1:
+		a.p = min(a.maxEntries, a.p+max(a.size(b2)/a.size(b1), 1))
+		a.replace(false)
+		e.Value.value = value
+		a.move(e, t2)
+		return
+
+	case ok && e.Value.list == b2:
+		a.p = max(0, a.p-max(a.size(b1)/a.size(b2), 1))
+		a.replace(true)
+		e.Value.value = value
+		a.move(e, t2)
+		return
+	}
+
+	switch l1 := a.size(t1) + a.size(b1); {
+	case l1 == a.maxEntries:
+		if a.size(t1) < a.maxEntries {
+			a.drop(b1)
+			a.replace(false)
+		} else {
+			a.drop(t1)
+		}
+
+	case l1 < a.maxEntries:
+		total := l1 + a.size(t2) + a.size(b2)
+		if total >= a.maxEntries {
+			if total == 2*a.maxEntries {
+				a.drop(b2)
+			}
+			a.replace(false)
+		}
+	}
+
+	a.entries[key] = a.lists[t1].PushFront(arcEntry[K, V]{
+		keyValue: keyValue[K, V]{key: key, value: value},
+		list:     t1,
+	})
+}
+
+func (a *arc[K, V]) remove(key K) (V, bool) {
+	e, ok := a.entries[key]
+	if !ok {
+		var empty V
+		return empty, false
+	}
+
+	a.lists[e.Value.list].Remove(e)
+	delete(a.entries, key)
+
+	return e.Value.value, e.Value.resident()
+}
+
+func (a *arc[K, V]) resize(maxEntries int) {
+	a.maxEntries = maxEntries
+	a.p = min(a.p, maxEntries)
+
+	for a.len() > a.maxEntries {
+		a.replace(false)
+	}
+
+	for a.size(t1)+a.size(b1) > a.maxEntries && a.size(b1) > 0 {
+		a.drop(b1)
+	}
+	for a.len()+a.size(b1)+a.size(b2) > 2*a.maxEntries && a.size(b2) > 0 {
+		a.drop(b2)
+	}
+}
+
+func (a *arc[K, V]) walk(fn func(K, V)) {
+	for _, l := range []arcList{t1, t2} {
+		for e := a.lists[l].Back(); e != nil; e = e.Prev() {
+			fn(e.Value.key, e.Value.value)
+		}
+	}
+}
+
+func (a *arc[K, V]) len() int {
+	return a.size(t1) + a.size(t2)
+}
+
+func (a *arc[K, V]) name() string {
+	return `arc`
+}
+
+// replace evicts the least recently used entry of t1 or t2 to its ghost list
+func (a *arc[K, V]) replace(inB2 bool) {
+	n := a.size(t1)
+	if n > 0 && (n > a.p || (inB2 && n == a.p)) {
+		a.toGhost(a.lists[t1].Back(), b1)
+	} else if a.size(t2) > 0 {
+		a.toGhost(a.lists[t2].Back(), b2)
+	}
+}
+
+// toGhost evicts e, keeping its key in ghost list l
+func (a *arc[K, V]) toGhost(e *list.Element[arcEntry[K, V]], l arcList) {
+	key, value := e.Value.key, e.Value.value
+	a.move(e, l)
+	a.evicted(key, value, ReasonCapacity)
+}
+
+// drop removes the least recently used entry of l entirely
+func (a *arc[K, V]) drop(l arcList) {
+	back := a.lists[l].Back()
+	if back == nil {
+		return
+	}
+
+	a.lists[l].Remove(back)
+	delete(a.entries, back.Value.key)
+
+	if back.Value.resident() {
+		a.evicted(back.Value.key, back.Value.value, ReasonCapacity)
+	}
+}
+
+// move moves e to the front of l, ghost entries do not keep their value
+func (a *arc[K, V]) move(e *list.Element[arcEntry[K, V]], l arcList) {
+	if e.Value.list == l {
+		a.lists[l].MoveToFront(e)
+		return
+	}
+
+	entry := a.lists[e.Value.list].Remove(e)
+	entry.list = l
+	if !entry.resident() {
+		var empty V
+		entry.value = empty
+	}
+
+	a.entries[entry.key] = a.lists[l].PushFront(entry)
+}
+
+func (a *arc[K, V]) size(l arcList) int {
+	return a.lists[l].Len()
+}
+
+func (e arcEntry[K, V]) resident() bool {
+	return e.list == t1 || e.list == t2
+}
@@ -0,0 +1,203 @@
+package cache_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/FallenTaters/cache"
+	"github.com/FallenTaters/cache/assert"
+)
+
+func newARC() cache.Cache[int, int] {
+	return cache.ARC[int, int](2)
+}
+
+func TestARC(t *testing.T) {
+	t.Run(`get non-existing`, func(t *testing.T) {
+		c := newARC()
+
+		v, ok := c.Get(1)
+		assert.False(t, ok)
+		assert.Equal(t, 0, v)
+	})
+
+	t.Run(`add and get`, func(t *testing.T) {
+		c := newARC()
+
+		c.Add(1, 1)
+
+		v, ok := c.Get(1)
+		assert.True(t, ok)
+		assert.Equal(t, 1, v)
+	})
+
+	t.Run(`recently used once out first`, func(t *testing.T) {
+		c := newARC()
+
+		_ = c.MustGetOrAdd(1, newAddFunc(1, nil))
+		_ = c.MustGetOrAdd(2, newAddFunc(2, nil))
+		_, _ = c.Get(1)
+		_ = c.MustGetOrAdd(3, newAddFunc(3, nil))
+
+		v, ok := c.Get(1)
+		assert.Equal(t, 1, v)
+		assert.True(t, ok)
+
+		v, ok = c.Get(2)
+		assert.Equal(t, 0, v)
+		assert.False(t, ok)
+
+		v, ok = c.Get(3)
+		assert.Equal(t, 3, v)
+		assert.True(t, ok)
+	})
+
+	t.Run(`shared load counts as one use`, func(t *testing.T) {
+		c := newARC()
+
+		loadConcurrently(t, c, 1, 1, 20)
+		c.Add(2, 2)
+		c.Add(3, 3)
+
+		_, ok := c.Get(1)
+		assert.False(t, ok)
+
+		v, ok := c.Get(2)
+		assert.Equal(t, 2, v)
+		assert.True(t, ok)
+	})
+
+	t.Run(`ghost hit in b1 favours recency`, func(t *testing.T) {
+		evictions, onEvict := recordEvictions()
+		c := cache.ARC(2, onEvict)
+
+		c.Add(1, 1)
+		c.Add(2, 2)
+		_, _ = c.Get(1)
+		c.Add(3, 3) // 2 is evicted from t1, its key is kept in b1
+
+		// the ghost hit grows the target size of t1, so the frequently used 1 is evicted from t2 instead of 3
+		c.Add(2, 2)
+
+		assert.Equal(t, 2, len(*evictions))
+		assert.Equal(t, evicted{2, 2, cache.ReasonCapacity}, (*evictions)[0])
+		assert.Equal(t, evicted{1, 1, cache.ReasonCapacity}, (*evictions)[1])
+
+		_, ok := c.Get(1)
+		assert.False(t, ok)
+
+		v, ok := c.Get(3)
+		assert.True(t, ok)
+		assert.Equal(t, 3, v)
+
+		v, ok = c.Get(2)
+		assert.True(t, ok)
+		assert.Equal(t, 2, v)
+	})
+
+	t.Run(`frequently used entries survive a scan`, func(t *testing.T) {
+		c := cache.ARC[int, int](100)
+
+		for i := 0; i < 2; i++ {
+			for key := 0; key < 50; key++ {
+				_ = c.MustGetOrAdd(key, newAddFunc(key, nil))
+			}
+		}
+
+		for key := 1000; key < 2000; key++ {
+			_ = c.MustGetOrAdd(key, newAddFunc(key, nil))
+		}
+
+		for key := 0; key < 50; key++ {
+			v, ok := c.Get(key)
+			assert.True(t, ok)
+			assert.Equal(t, key, v)
+		}
+	})
+
+	t.Run(`limit number of entries`, func(t *testing.T) {
+		c := cache.ARC[int, int](10)
+
+		for i := 0; i < 5; i++ {
+			for key := 0; key < 30; key++ {
+				_ = c.MustGetOrAdd(key*(i%3+1), newAddFunc(key, nil))
+			}
+		}
+
+		var found int
+		for key := 0; key < 90; key++ {
+			if _, ok := c.Get(key); ok {
+				found++
+			}
+		}
+		assert.True(t, found <= 10)
+	})
+
+	t.Run(`return error`, func(t *testing.T) {
+		myErr := errors.New(`myErr`)
+		c := newARC()
+
+		_, err := c.GetOrAdd(1, newAddFunc(0, myErr))
+		assert.ErrorIs(t, myErr, err)
+	})
+
+	t.Run(`only call addFunc when necessary`, func(t *testing.T) {
+		c := newARC()
+
+		addFunc, called := calledAddFunc(1, nil)
+
+		c.MustGetOrAdd(1, addFunc)
+		assert.True(t, *called)
+
+		*called = false
+		c.MustGetOrAdd(1, addFunc)
+		assert.False(t, *called)
+	})
+
+	t.Run(`delete`, func(t *testing.T) {
+		c := newARC()
+
+		_ = c.MustGetOrAdd(1, newAddFunc(1, nil))
+		_, _ = c.Get(1)
+
+		c.Delete(1)
+
+		v, ok := c.Get(1)
+		assert.False(t, ok)
+		assert.Equal(t, 0, v)
+	})
+
+	t.Run(`concurrent operations`, func(t *testing.T) {
+		c := newARC()
+
+		var wg sync.WaitGroup
+
+		wg.Add(concurrentCount)
+		go func() {
+			for i := 0; i < concurrentCount; i++ {
+				expected := i
+				go func() {
+					actual, err := c.GetOrAdd(expected, newAddFunc(expected, nil))
+					assert.Equal(t, expected, actual)
+					assert.NoError(t, err)
+
+					wg.Done()
+				}()
+			}
+		}()
+
+		wg.Add(concurrentCount)
+		go func() {
+			for i := concurrentCount - 1; i >= 0; i-- {
+				expected := i
+				go func() {
+					c.Delete(expected)
+					wg.Done()
+				}()
+			}
+		}()
+
+		wg.Wait()
+	})
+}
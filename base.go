@@ -0,0 +1,497 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"io"
+	"iter"
+	"sync"
+)
+
+// policy decides which entries are kept, all methods are called with the cache locked
+type policy[K comparable, V any] interface {
+	// get returns the value for key and records the access
+	get(K) (V, bool)
+
+	// peek returns the value for key without recording the access
+	peek(K) (V, bool)
+
+	// readOnlyGet returns true if get may be called with only the read lock held
+	readOnlyGet() bool
+
+	// add adds or replaces the value for key, evicting another entry if the cache is full
+	add(K, V)
+
+	// remove removes the entry for key if it exists and returns its value
+	remove(K) (V, bool)
+
+	// walk calls fn for every entry, in the order in which they would be evicted
+	walk(fn func(K, V))
+
+	// resize changes the maximum size, evicting entries right away if the cache is too large
+	resize(int)
+
+	// len returns the number of entries
+	len() int
+
+	// name returns the name of the policy
+	name() string
+
+	// setOnEvict sets the function called for entries that the policy evicts or replaces
+	setOnEvict(func(K, V, EvictionReason))
+}
+
+// base implements Cache by locking around a policy
+type base[K comparable, V any] struct {
+	sync.RWMutex
+
+	adder addManager[K, V]
+
+	policy policy[K, V]
+
+	codec Codec
+
+	onEvict   func(K, V, EvictionReason)
+	expired   func(V) bool
+	hidden    func(V) bool
+	evictions []eviction[K, V]
+
+	// onChange is called for every key whose entry was added, replaced or removed, see watch
+	onChange func(K)
+	changed  []K
+
+	stats stats
+}
+
+func newBase[K comparable, V any](p policy[K, V], opts []Option[K, V]) *base[K, V] {
+	o := newOptions(opts)
+
+	b := &base[K, V]{
+		adder: addManager[K, V]{
+			busyKeys: make(map[K]*call[V]),
+		},
+		policy:  p,
+		codec:   o.codec,
+		onEvict: o.onEvict,
+		expired: o.expired,
+		hidden:  o.hidden,
+	}
+	p.setOnEvict(b.evicted)
+
+	return b
+}
+
+func (b *base[K, V]) Get(key K) (V, bool) {
+	if b.policy.readOnlyGet() {
+		b.RLock()
+		defer b.RUnlock()
+	} else {
+		b.Lock()
+		defer b.Unlock()
+	}
+
+	v, ok := b.policy.get(key)
+	b.stats.lookup(ok)
+
+	return v, ok
+}
+
+func (b *base[K, V]) GetMany(keys []K) map[K]V {
+	if b.policy.readOnlyGet() {
+		b.RLock()
+		defer b.RUnlock()
+	} else {
+		b.Lock()
+		defer b.Unlock()
+	}
+
+	values := make(map[K]V, len(keys))
+	for _, key := range keys {
+		v, ok := b.policy.get(key)
+		b.stats.lookup(ok)
+		if ok {
+			values[key] = v
+		}
+	}
+
+	return values
+}
+
+func (b *base[K, V]) Add(key K, value V) {
+	b.update(func() {
+		b.add(key, value)
+	})
+}
+
+func (b *base[K, V]) AddMany(values map[K]V) {
+	b.update(func() {
+		for key, value := range values {
+			b.add(key, value)
+		}
+	})
+}
+
+func (b *base[K, V]) GetOrAdd(key K, addFunc AddFunc[V]) (V, error) {
+	return b.GetOrAddCtx(context.Background(), key, addFunc.withContext())
+}
+
+func (b *base[K, V]) GetOrAddCtx(ctx context.Context, key K, addFunc AddFuncCtx[V]) (V, error) {
+	v, ok := b.Get(key)
+	if ok {
+		return v, nil
+	}
+
+	return b.load(ctx, key, addFunc)
+}
+
+// load runs addFunc, or waits for the addFunc already running for key.
+// The result is added once by the addFunc, not by every waiter
+func (b *base[K, V]) load(ctx context.Context, key K, addFunc AddFuncCtx[V]) (V, error) {
+	result := b.adder.wait(ctx, key, timed(&b.stats, addFunc), b.added(key))
+
+	return result.Value, result.Err
+}
+
+func (b *base[K, V]) GetOrAddMany(keys []K, addFunc BatchAddFunc[K, V]) (map[K]V, error) {
+	return b.getOrAddMany(keys, addFunc, nil)
+}
+
+// getOrAddMany is GetOrAddMany, but if claimed is not nil, it gets the keys that will be passed to addFunc
+// before getOrAddMany waits for anything
+func (b *base[K, V]) getOrAddMany(keys []K, addFunc BatchAddFunc[K, V], claimed func([]K)) (map[K]V, error) {
+	values := b.GetMany(keys)
+
+	var missing []K
+	for _, key := range keys {
+		if _, ok := values[key]; !ok {
+			missing = append(missing, key)
+		}
+	}
+
+	loaded, err := b.loadMany(context.Background(), missing, func(_ context.Context, missing []K) (map[K]V, error) {
+		return addFunc(missing)
+	}, claimed)
+	for key, v := range loaded {
+		values[key] = v
+	}
+
+	return values, err
+}
+
+// loadMany is like load for many keys, running addFunc once for all keys that are not being loaded yet.
+// It returns the first error other than ErrMissingKey. claimed is passed on to waitMany
+func (b *base[K, V]) loadMany(ctx context.Context, keys []K, addFunc batchAddFuncCtx[K, V], claimed func([]K)) (map[K]V, error) {
+	if len(keys) == 0 {
+		if claimed != nil {
+			claimed(nil)
+		}
+		return nil, nil
+	}
+
+	results := b.adder.waitMany(ctx, keys, func(ctx context.Context, keys []K) (map[K]V, error) {
+		return timed(&b.stats, func(ctx context.Context) (map[K]V, error) {
+			return addFunc(ctx, keys)
+		})(ctx)
+	}, b.AddMany, claimed)
+
+	var err error
+	values := make(map[K]V, len(results))
+	for key, result := range results {
+		switch {
+		case result.Err == nil:
+			values[key] = result.Value
+		case err == nil && !errors.Is(result.Err, ErrMissingKey):
+			err = result.Err
+		}
+	}
+
+	return values, err
+}
+
+// refresh runs addFunc in the background and adds its result, unless an addFunc is already running for key
+func (b *base[K, V]) refresh(key K, addFunc AddFuncCtx[V]) {
+	b.adder.refresh(key, timed(&b.stats, addFunc), b.added(key))
+}
+
+// added returns a callback that adds the result of a successful addFunc for key
+func (b *base[K, V]) added(key K) func(result[V]) {
+	return func(result result[V]) {
+		if result.Err == nil {
+			b.Add(key, result.Value)
+		}
+	}
+}
+
+func (b *base[K, V]) MustGetOrAdd(key K, addFunc AddFunc[V]) V {
+	v, err := b.GetOrAdd(key, addFunc)
+	if err != nil {
+		panic(err)
+	}
+
+	return v
+}
+
+func (b *base[K, V]) Delete(key K) {
+	b.update(func() {
+		b.remove(key, ReasonDeleted)
+	})
+}
+
+func (b *base[K, V]) DeleteMany(keys []K) {
+	b.update(func() {
+		for _, key := range keys {
+			b.remove(key, ReasonDeleted)
+		}
+	})
+}
+
+func (b *base[K, V]) Compute(key K, fn ComputeFunc[V]) (V, bool) {
+	var v V
+	var ok bool
+	b.update(func() {
+		old, found := b.policy.peek(key)
+		value, action := fn(old, found)
+
+		switch action {
+		case ActionStore:
+			b.add(key, value)
+		case ActionDelete:
+			b.remove(key, ReasonDeleted)
+		}
+
+		// the value may be evicted right away if it does not fit
+		v, ok = b.policy.peek(key)
+	})
+
+	return v, ok
+}
+
+func (b *base[K, V]) AddIfAbsent(key K, value V) bool {
+	added := false
+	b.Compute(key, func(old V, found bool) (V, Action) {
+		if found {
+			return old, ActionKeep
+		}
+
+		added = true
+		return value, ActionStore
+	})
+
+	return added
+}
+
+func (b *base[K, V]) Clear() {
+	b.deleteFunc(func(K, V) bool {
+		return true
+	}, ReasonCleared)
+}
+
+func (b *base[K, V]) Resize(maxEntries int) {
+	b.update(func() {
+		b.policy.resize(max(maxEntries, 0))
+	})
+}
+
+func (b *base[K, V]) Len() int {
+	b.RLock()
+	defer b.RUnlock()
+
+	return b.policy.len()
+}
+
+func (b *base[K, V]) Peek(key K) (V, bool) {
+	b.RLock()
+	defer b.RUnlock()
+
+	return b.policy.peek(key)
+}
+
+func (b *base[K, V]) Keys() []K {
+	b.RLock()
+	defer b.RUnlock()
+
+	keys := make([]K, 0, b.policy.len())
+	b.policy.walk(func(key K, _ V) {
+		keys = append(keys, key)
+	})
+
+	return keys
+}
+
+func (b *base[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		b.RLock()
+		entries := make([]keyValue[K, V], 0, b.policy.len())
+		b.policy.walk(func(key K, value V) {
+			entries = append(entries, keyValue[K, V]{key, value})
+		})
+		b.RUnlock()
+
+		for _, e := range entries {
+			if !yield(e.key, e.value) {
+				return
+			}
+		}
+	}
+}
+
+func (b *base[K, V]) Save(w io.Writer) error {
+	return encodeEntries(b.codec, w, b.entries())
+}
+
+func (b *base[K, V]) Load(r io.Reader) error {
+	entries, err := decodeEntries[K, V](b.codec, r)
+	if err != nil {
+		return err
+	}
+
+	b.restore(entries)
+	return nil
+}
+
+func (b *base[K, V]) entries() []entry[K, V] {
+	b.RLock()
+	defer b.RUnlock()
+
+	entries := make([]entry[K, V], 0, b.policy.len())
+	b.policy.walk(func(key K, value V) {
+		entries = append(entries, entry[K, V]{Key: key, Value: value})
+	})
+
+	return entries
+}
+
+func (b *base[K, V]) peekEntry(key K) (entry[K, V], bool) {
+	v, ok := b.Peek(key)
+	return entry[K, V]{Key: key, Value: v}, ok
+}
+
+func (b *base[K, V]) restore(entries []entry[K, V]) {
+	b.update(func() {
+		for _, e := range entries {
+			b.add(e.Key, e.Value)
+		}
+	})
+}
+
+func (b *base[K, V]) Stats() Stats {
+	b.RLock()
+	entries := b.policy.len()
+	b.RUnlock()
+
+	s := b.stats.snapshot()
+	s.Entries = entries
+
+	return s
+}
+
+func (b *base[K, V]) ResetStats() {
+	b.stats.reset()
+}
+
+func (b *base[K, V]) Policy() string {
+	return b.policy.name()
+}
+
+// deleteIf removes the entries for keys if del returns true for their value
+func (b *base[K, V]) deleteIf(del func(V) bool, reason EvictionReason, keys ...K) {
+	b.update(func() {
+		for _, key := range keys {
+			if v, ok := b.policy.peek(key); ok && del(v) {
+				b.remove(key, reason)
+			}
+		}
+	})
+}
+
+// deleteFunc removes all entries for which del returns true, in eviction order
+func (b *base[K, V]) deleteFunc(del func(K, V) bool, reason EvictionReason) {
+	b.update(func() {
+		var keys []K
+		b.policy.walk(func(key K, value V) {
+			if del(key, value) {
+				keys = append(keys, key)
+			}
+		})
+
+		for _, key := range keys {
+			b.remove(key, reason)
+		}
+	})
+}
+
+// watch makes b call onChange after every change to the entry of a key
+func (b *base[K, V]) watch(onChange func(K)) bool {
+	b.Lock()
+	defer b.Unlock()
+
+	b.onChange = onChange
+	return true
+}
+
+// update runs fn with b locked, then calls onEvict for all entries evicted by fn
+func (b *base[K, V]) update(fn func()) {
+	var evictions []eviction[K, V]
+	var changed []K
+
+	func() {
+		b.Lock()
+		// fn can panic in a ComputeFunc or Weigher, which must not leave b locked
+		defer func() {
+			evictions, changed = b.evictions, b.changed
+			b.evictions, b.changed = nil, nil
+			b.Unlock()
+		}()
+
+		fn()
+	}()
+
+	for _, e := range evictions {
+		b.onEvict(e.key, e.value, e.reason)
+	}
+	for _, key := range changed {
+		b.onChange(key)
+	}
+}
+
+// add adds value for key to the policy and records the change, b must be locked
+func (b *base[K, V]) add(key K, value V) {
+	b.policy.add(key, value)
+	b.record(key)
+}
+
+// remove removes the entry for key and records its eviction, b must be locked
+func (b *base[K, V]) remove(key K, reason EvictionReason) {
+	if v, ok := b.policy.remove(key); ok {
+		b.evicted(key, v, reason)
+	}
+}
+
+// evicted counts an eviction and records it, so onEvict can be called after b is unlocked
+func (b *base[K, V]) evicted(key K, value V, reason EvictionReason) {
+	// a replaced entry is recorded by add
+	if reason != ReasonReplaced {
+		b.record(key)
+	}
+
+	if b.hidden != nil && b.hidden(value) {
+		return
+	}
+
+	if reason == ReasonCapacity && b.expired != nil && b.expired(value) {
+		reason = ReasonExpired
+	}
+
+	b.stats.evicted(reason)
+
+	if b.onEvict != nil {
+		b.evictions = append(b.evictions, eviction[K, V]{key, value, reason})
+	}
+}
+
+// record keeps key, so onChange can be called after b is unlocked
+func (b *base[K, V]) record(key K) {
+	if b.onChange != nil {
+		b.changed = append(b.changed, key)
+	}
+}
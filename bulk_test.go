@@ -0,0 +1,347 @@
+package cache_test
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/FallenTaters/cache"
+	"github.com/FallenTaters/cache/assert"
+)
+
+func TestBulk(t *testing.T) {
+	caches := map[string]func() cache.Cache[int, int]{
+		`fifo`:    func() cache.Cache[int, int] { return cache.FIFO[int, int](10) },
+		`lru`:     func() cache.Cache[int, int] { return cache.LRU[int, int](10) },
+		`lfu`:     func() cache.Cache[int, int] { return cache.LFU[int, int](10) },
+		`tinylfu`: func() cache.Cache[int, int] { return cache.TinyLFU[int, int](10) },
+		`arc`:     func() cache.Cache[int, int] { return cache.ARC[int, int](10) },
+		`sieve`:   func() cache.Cache[int, int] { return cache.SIEVE[int, int](10) },
+		`s3fifo`:  func() cache.Cache[int, int] { return cache.S3FIFO[int, int](10) },
+		`tlru`:    func() cache.Cache[int, int] { return cache.TLRU[int, int](10, time.Hour) },
+		`sharded`: func() cache.Cache[int, int] {
+			return cache.Sharded(4, func() cache.Cache[int, int] { return cache.LRU[int, int](10) })
+		},
+		`sharded tlru`: func() cache.Cache[int, int] {
+			return cache.Sharded(4, func() cache.Cache[int, int] { return cache.TLRU[int, int](10, time.Hour) })
+		},
+	}
+
+	for name, newCache := range caches {
+		t.Run(name, func(t *testing.T) {
+			c := newCache()
+
+			c.AddMany(map[int]int{1: 1, 2: 2, 3: 3})
+
+			values := c.GetMany([]int{1, 2, 4})
+			assert.Equal(t, 2, len(values))
+			assert.Equal(t, 1, values[1])
+			assert.Equal(t, 2, values[2])
+
+			s := c.Stats()
+			assert.Equal(t, uint64(2), s.Hits)
+			assert.Equal(t, uint64(1), s.Misses)
+			assert.Equal(t, 3, s.Entries)
+
+			c.DeleteMany([]int{1, 3, 4})
+
+			values = c.GetMany([]int{1, 2, 3})
+			assert.Equal(t, 1, len(values))
+			assert.Equal(t, 2, values[2])
+			assert.Equal(t, uint64(2), c.Stats().Evictions[cache.ReasonDeleted])
+		})
+	}
+
+	t.Run(`onEvict is called for replaced and deleted entries`, func(t *testing.T) {
+		evictions, onEvict := recordEvictions()
+		c := cache.FIFO(2, onEvict)
+
+		c.AddMany(map[int]int{1: 1, 2: 2})
+		c.AddMany(map[int]int{1: 10})
+		c.DeleteMany([]int{2})
+
+		assert.Equal(t, 2, len(*evictions))
+	})
+
+	t.Run(`expired entries are missing`, func(t *testing.T) {
+		evictions, onEvict := recordEvictions()
+		c := cache.TFIFO(3, cacheDuration, onEvict)
+
+		c.AddMany(map[int]int{1: 1, 2: 2})
+		sleep()
+		c.Add(3, 3)
+
+		values := c.GetMany([]int{1, 2, 3})
+		assert.Equal(t, 1, len(values))
+		assert.Equal(t, 3, values[3])
+
+		s := c.Stats()
+		assert.Equal(t, uint64(1), s.Hits)
+		assert.Equal(t, uint64(2), s.Misses)
+		assert.Equal(t, 1, s.Entries)
+		assert.Equal(t, uint64(2), s.Evictions[cache.ReasonExpired])
+		assert.Equal(t, 2, len(*evictions))
+	})
+}
+
+func TestGetOrAddMany(t *testing.T) {
+	t.Run(`load missing keys in one batch`, func(t *testing.T) {
+		c := cache.LRU[int, int](10)
+		c.Add(1, 1)
+
+		var batches [][]int
+		values, err := c.GetOrAddMany([]int{1, 2, 3, 4}, func(missing []int) (map[int]int, error) {
+			batches = append(batches, missing)
+			return map[int]int{2: 2, 3: 3}, nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 3, len(values))
+		assert.Equal(t, 1, len(batches))
+		assert.Equal(t, 3, len(batches[0]))
+
+		v, ok := c.Get(3)
+		assert.True(t, ok)
+		assert.Equal(t, 3, v)
+
+		_, ok = c.Get(4)
+		assert.False(t, ok, `keys missing from the batch are not added`)
+
+		s := c.Stats()
+		assert.Equal(t, uint64(1), s.Loads)
+	})
+
+	t.Run(`return hits with error`, func(t *testing.T) {
+		myErr := errors.New(`myErr`)
+		c := cache.FIFO[int, int](10)
+		c.Add(1, 1)
+
+		values, err := c.GetOrAddMany([]int{1, 2}, func([]int) (map[int]int, error) {
+			return nil, myErr
+		})
+		assert.ErrorIs(t, myErr, err)
+		assert.Equal(t, 1, len(values))
+		assert.Equal(t, 1, values[1])
+	})
+
+	t.Run(`GetOrAdd waits for batch`, func(t *testing.T) {
+		c := cache.LRU[int, int](10)
+
+		started := make(chan struct{})
+		release := make(chan struct{})
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			_, err := c.GetOrAddMany([]int{1, 2}, func([]int) (map[int]int, error) {
+				close(started)
+				<-release
+				return map[int]int{1: 1}, nil
+			})
+			assert.NoError(t, err)
+		}()
+		<-started
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			addFunc, called := calledAddFunc(10, nil)
+			v, err := c.GetOrAdd(1, addFunc)
+			assert.NoError(t, err)
+			assert.Equal(t, 1, v)
+			assert.False(t, *called)
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := c.GetOrAdd(2, newAddFunc(20, nil))
+			if err != nil {
+				assert.ErrorIs(t, cache.ErrMissingKey, err)
+			}
+		}()
+
+		time.Sleep(cacheDuration)
+		close(release)
+		wg.Wait()
+		<-done
+	})
+
+	t.Run(`batch waits for GetOrAdd`, func(t *testing.T) {
+		c := cache.LRU[int, int](10)
+
+		started := make(chan struct{})
+		release := make(chan struct{})
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			v, err := c.GetOrAdd(1, func() (int, error) {
+				close(started)
+				<-release
+				return 1, nil
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, 1, v)
+		}()
+		<-started
+
+		go func() {
+			time.Sleep(cacheDuration)
+			close(release)
+		}()
+
+		values, err := c.GetOrAddMany([]int{1, 2, 2}, func(missing []int) (map[int]int, error) {
+			assert.Equal(t, 1, len(missing))
+			assert.Equal(t, 2, missing[0])
+			return map[int]int{2: 2}, nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 2, len(values))
+		assert.Equal(t, 1, values[1])
+		assert.Equal(t, 2, values[2])
+		<-done
+	})
+
+	t.Run(`sharded batch waits for GetOrAdd`, func(t *testing.T) {
+		c := cache.Sharded(4, func() cache.Cache[int, int] { return cache.LRU[int, int](10) })
+
+		started := make(chan struct{})
+		release := make(chan struct{})
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			v, err := c.GetOrAdd(1, func() (int, error) {
+				close(started)
+				<-release
+				return 1, nil
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, 1, v)
+		}()
+		<-started
+
+		go func() {
+			time.Sleep(cacheDuration)
+			close(release)
+		}()
+
+		var calls int
+		values, err := c.GetOrAddMany([]int{1, 2, 3, 4, 5}, func(missing []int) (map[int]int, error) {
+			calls++
+			assert.Equal(t, 4, len(missing))
+			assert.False(t, slices.Contains(missing, 1), `key 1 is loaded by GetOrAdd`)
+
+			values := make(map[int]int)
+			for _, key := range missing {
+				values[key] = key
+			}
+			return values, nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, calls)
+		assert.Equal(t, 5, len(values))
+		assert.Equal(t, 1, values[1])
+		<-done
+	})
+
+	t.Run(`overlapping sharded batches`, func(t *testing.T) {
+		c := cache.Sharded(4, func() cache.Cache[int, int] { return cache.LRU[int, int](100) })
+
+		var wg sync.WaitGroup
+		wg.Add(20)
+		for i := 0; i < 20; i++ {
+			go func() {
+				defer wg.Done()
+
+				keys := []int{i % 5, i%5 + 5, 10 - i%5, 20}
+				values, err := c.GetOrAddMany(keys, func(missing []int) (map[int]int, error) {
+					time.Sleep(time.Millisecond)
+
+					values := make(map[int]int)
+					for _, key := range missing {
+						values[key] = key
+					}
+					return values, nil
+				})
+				assert.NoError(t, err)
+				for _, key := range keys {
+					assert.Equal(t, key, values[key])
+				}
+			}()
+		}
+		wg.Wait()
+	})
+
+	t.Run(`batch shares a cached error`, func(t *testing.T) {
+		errNotFound := errors.New(`not found`)
+		c := cache.TLRU(10, time.Hour, cache.WithErrorCaching[int, int](time.Hour, nil))
+
+		started := make(chan struct{})
+		release := make(chan struct{})
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			_, err := c.GetOrAdd(1, func() (int, error) {
+				close(started)
+				<-release
+				return 0, errNotFound
+			})
+			assert.ErrorIs(t, errNotFound, err)
+		}()
+		<-started
+
+		go func() {
+			time.Sleep(cacheDuration)
+			close(release)
+		}()
+
+		values, err := c.GetOrAddMany([]int{1, 2}, func(missing []int) (map[int]int, error) {
+			return map[int]int{2: 2}, nil
+		})
+		assert.ErrorIs(t, errNotFound, err)
+		assert.Equal(t, 1, len(values))
+		assert.Equal(t, 2, values[2])
+		<-done
+	})
+
+	t.Run(`reload expired values`, func(t *testing.T) {
+		c := cache.TLRU[int, int](10, cacheDuration)
+		c.Add(1, 1)
+		c.Add(2, 2)
+		sleep()
+		c.Add(2, 2)
+
+		values, err := c.GetOrAddMany([]int{1, 2}, func(missing []int) (map[int]int, error) {
+			assert.Equal(t, 1, len(missing))
+			return map[int]int{1: 10}, nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 10, values[1])
+		assert.Equal(t, 2, values[2])
+
+		s := c.Stats()
+		assert.Equal(t, uint64(1), s.Hits)
+		assert.Equal(t, uint64(1), s.Misses)
+		assert.Equal(t, uint64(1), s.Evictions[cache.ReasonExpired])
+	})
+
+	t.Run(`sharded`, func(t *testing.T) {
+		c := cache.Sharded(4, func() cache.Cache[int, int] { return cache.LRU[int, int](10) })
+		c.Add(1, 1)
+
+		var calls int
+		values, err := c.GetOrAddMany([]int{1, 2, 3, 4, 5, 6, 7, 8}, func(missing []int) (map[int]int, error) {
+			calls++
+			assert.Equal(t, 7, len(missing))
+
+			values := make(map[int]int)
+			for _, key := range missing {
+				values[key] = key
+			}
+			return values, nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, calls, `one call for all shards`)
+		assert.Equal(t, 8, len(values))
+		assert.Equal(t, 8, c.Stats().Entries)
+	})
+}
@@ -1,5 +1,12 @@
 package cache
 
+import (
+	"context"
+	"io"
+	"iter"
+	"time"
+)
+
 type Cache[K comparable, V any] interface {
 	// Get returns true if the value is found
 	Get(K) (V, bool)
@@ -11,11 +18,124 @@ type Cache[K comparable, V any] interface {
 	// if AddFunc returns an error, GetOrAdd returns it
 	GetOrAdd(K, AddFunc[V]) (V, error)
 
+	// GetOrAddCtx is like GetOrAdd, but returns ctx.Err() as soon as ctx is done.
+	// The context passed to AddFuncCtx is cancelled only when all callers waiting for it are gone
+	GetOrAddCtx(context.Context, K, AddFuncCtx[V]) (V, error)
+
 	// MustGetOrAdd is like GetOrAdd, but will panic if AddFunc returns an error
 	MustGetOrAdd(K, AddFunc[V]) V
 
+	// GetOrAddMany returns the values that are found and runs BatchAddFunc once for all missing keys.
+	// Keys that are already being loaded by GetOrAdd or another GetOrAddMany are not passed to BatchAddFunc,
+	// their results are shared instead. Keys without a value in the result are left out.
+	// If BatchAddFunc returns an error, GetOrAddMany returns the values it has along with the error
+	GetOrAddMany([]K, BatchAddFunc[K, V]) (map[K]V, error)
+
 	// Delete removes the cached entry if it exists
 	Delete(K)
+
+	// Compute runs ComputeFunc with the current value and stores or deletes its result as one atomic step.
+	// It returns the value for the key afterwards, and false if there is none
+	Compute(K, ComputeFunc[V]) (V, bool)
+
+	// AddIfAbsent adds the value if the key is not cached yet, and returns true if it did
+	AddIfAbsent(K, V) bool
+
+	// GetMany returns the values that are found, locking the cache once
+	GetMany([]K) map[K]V
+
+	// AddMany adds all values to the cache, locking the cache once
+	AddMany(map[K]V)
+
+	// DeleteMany removes the cached entries that exist, locking the cache once
+	DeleteMany([]K)
+
+	// Clear removes all entries
+	Clear()
+
+	// Resize changes maxEntries, or maxCost for weighted caches. When shrinking, entries are evicted right away.
+	// Sizes below 0 are treated as 0
+	Resize(int)
+
+	// Len returns the number of cached entries
+	Len() int
+
+	// Peek is like Get, but does not count as a use of the entry for eviction or Stats
+	Peek(K) (V, bool)
+
+	// Keys returns the cached keys, in the order in which they would be evicted
+	Keys() []K
+
+	// All returns an iterator over a snapshot of the cached entries, in the order in which they would be evicted.
+	// The snapshot is taken when iteration starts, so the loop body may use the cache
+	All() iter.Seq2[K, V]
+
+	// Save writes all entries to the writer in eviction order, using the Codec set with WithCodec
+	Save(io.Writer) error
+
+	// Load adds the entries written by Save, keeping their order. Entries that are already cached stay,
+	// unless Load replaces them or evicts them to make room
+	Load(io.Reader) error
+
+	// Stats returns the counters of the cache
+	Stats() Stats
+
+	// ResetStats sets all counters to zero, Stats().Entries is not affected
+	ResetStats()
+
+	// Policy returns the name of the eviction policy, such as "lru" or "tfifo"
+	Policy() string
+}
+
+// TCache is a time-aware Cache, where each entry expires after its own maximum age
+type TCache[K comparable, V any] interface {
+	Cache[K, V]
+
+	// AddWithTTL is like Add, but the entry expires after ttl instead of the default maxAge
+	AddWithTTL(K, V, time.Duration)
+
+	// GetOrAddWithTTL is like GetOrAdd, but a newly added entry expires after ttl
+	GetOrAddWithTTL(K, AddFunc[V], time.Duration) (V, error)
+
+	// GetOrAddTTLFunc is like GetOrAdd, but TTLAddFunc decides how long the new entry lives
+	GetOrAddTTLFunc(K, TTLAddFunc[V]) (V, error)
+
+	// PurgeExpired removes all expired entries now, like the janitor does
+	PurgeExpired()
+
+	// Close stops background work started by options such as WithJanitor
+	Close()
 }
 
 type AddFunc[V any] func() (V, error)
+
+func (addFunc AddFunc[V]) withContext() AddFuncCtx[V] {
+	return func(context.Context) (V, error) {
+		return addFunc()
+	}
+}
+
+// AddFuncCtx is an AddFunc that should stop when its context is cancelled
+type AddFuncCtx[V any] func(context.Context) (V, error)
+
+// BatchAddFunc is an AddFunc for many keys at once, it returns the values of the keys that exist
+type BatchAddFunc[K comparable, V any] func(missing []K) (map[K]V, error)
+
+// batchAddFuncCtx is a BatchAddFunc that should stop when its context is cancelled
+type batchAddFuncCtx[K comparable, V any] func(context.Context, []K) (map[K]V, error)
+
+// TTLAddFunc is an AddFunc that also returns the maximum age of the value,
+// for example taken from a Cache-Control header
+type TTLAddFunc[V any] func() (V, time.Duration, error)
+
+// Weigher returns the cost of a key-value pair, for example its size in bytes
+type Weigher[K comparable, V any] func(K, V) int64
+
+// weigh returns the cost of a pair, or 1 if there is no Weigher
+func (weigher Weigher[K, V]) weigh(key K, value V) int64 {
+	if weigher == nil {
+		return 1
+	}
+
+	return weigher(key, value)
+}
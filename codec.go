@@ -0,0 +1,130 @@
+package cache
+
+import (
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+)
+
+// Codec encodes the entries written by Save and decodes them for Load
+type Codec interface {
+	NewEncoder(io.Writer) Encoder
+	NewDecoder(io.Reader) Decoder
+}
+
+// Encoder writes values to a stream, such as *gob.Encoder or *json.Encoder
+type Encoder interface {
+	Encode(any) error
+}
+
+// Decoder reads values from a stream, such as *gob.Decoder or *json.Decoder.
+// Decode must return io.EOF at the end of the stream
+type Decoder interface {
+	Decode(any) error
+}
+
+var (
+	// GobCodec uses encoding/gob, it is the default Codec
+	GobCodec Codec = gobCodec{}
+
+	// JSONCodec uses encoding/json and writes one entry per line
+	JSONCodec Codec = jsonCodec{}
+)
+
+type gobCodec struct{}
+
+func (gobCodec) NewEncoder(w io.Writer) Encoder { return gob.NewEncoder(w) }
+func (gobCodec) NewDecoder(r io.Reader) Decoder { return gob.NewDecoder(r) }
+
+type jsonCodec struct{}
+
+func (jsonCodec) NewEncoder(w io.Writer) Encoder { return json.NewEncoder(w) }
+func (jsonCodec) NewDecoder(r io.Reader) Decoder { return json.NewDecoder(r) }
+
+// entry is a key-value pair as written by Save, Added and MaxAge are only used by time-aware caches
+type entry[K comparable, V any] struct {
+	Key    K
+	Value  V
+	Added  time.Time     `json:",omitzero"`
+	MaxAge time.Duration `json:",omitzero"`
+}
+
+// persister is implemented by the caches of this package, so Sharded can save and load its shards as one stream
+type persister[K comparable, V any] interface {
+	// entries returns a snapshot of all entries, in the order in which they would be evicted
+	entries() []entry[K, V]
+
+	// restore adds entries in order, so the last one is the most recent
+	restore([]entry[K, V])
+
+	// peekEntry returns the entry for key, like Peek
+	peekEntry(K) (entry[K, V], bool)
+}
+
+// entriesOf returns the entries of c, without timestamps if c is not a persister
+func entriesOf[K comparable, V any](c Cache[K, V]) []entry[K, V] {
+	if p, ok := c.(persister[K, V]); ok {
+		return p.entries()
+	}
+
+	var entries []entry[K, V]
+	for key, value := range c.All() {
+		entries = append(entries, entry[K, V]{Key: key, Value: value})
+	}
+
+	return entries
+}
+
+// peekEntryOf returns the entry for key in c, without timestamps if c is not a persister
+func peekEntryOf[K comparable, V any](c Cache[K, V], key K) (entry[K, V], bool) {
+	if p, ok := c.(persister[K, V]); ok {
+		return p.peekEntry(key)
+	}
+
+	v, ok := c.Peek(key)
+	return entry[K, V]{Key: key, Value: v}, ok
+}
+
+// restoreTo adds entries to c, ignoring timestamps if c is not a persister
+func restoreTo[K comparable, V any](c Cache[K, V], entries []entry[K, V]) {
+	if p, ok := c.(persister[K, V]); ok {
+		p.restore(entries)
+		return
+	}
+
+	for _, e := range entries {
+		c.Add(e.Key, e.Value)
+	}
+}
+
+func encodeEntries[K comparable, V any](codec Codec, w io.Writer, entries []entry[K, V]) error {
+	enc := codec.NewEncoder(w)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// decodeEntries reads entries until the end of r
+func decodeEntries[K comparable, V any](codec Codec, r io.Reader) ([]entry[K, V], error) {
+	dec := codec.NewDecoder(r)
+
+	var entries []entry[K, V]
+	for {
+		var e entry[K, V]
+		err := dec.Decode(&e)
+		if errors.Is(err, io.EOF) {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, e)
+	}
+}
@@ -0,0 +1,103 @@
+package cache_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/FallenTaters/cache"
+	"github.com/FallenTaters/cache/assert"
+)
+
+func TestSaveLoad(t *testing.T) {
+	t.Run(`keep eviction order`, func(t *testing.T) {
+		c := cache.LRU[int, int](10)
+		c.Add(1, 1)
+		c.Add(2, 2)
+		c.Add(3, 3)
+		c.Get(1)
+
+		var buf bytes.Buffer
+		assert.NoError(t, c.Save(&buf))
+
+		loaded := cache.LRU[int, int](10)
+		assert.NoError(t, loaded.Load(&buf))
+		assertKeys(t, []int{2, 3, 1}, loaded.Keys())
+
+		v, ok := loaded.Get(3)
+		assert.True(t, ok)
+		assert.Equal(t, 3, v)
+	})
+
+	t.Run(`json`, func(t *testing.T) {
+		type item struct {
+			Name string
+		}
+
+		c := cache.FIFO(10, cache.WithCodec[string, item](cache.JSONCodec))
+		c.Add(`a`, item{`A`})
+		c.Add(`b`, item{`B`})
+
+		var buf bytes.Buffer
+		assert.NoError(t, c.Save(&buf))
+		assert.Equal(t, 2, strings.Count(buf.String(), "\n"))
+
+		loaded := cache.FIFO(10, cache.WithCodec[string, item](cache.JSONCodec))
+		assert.NoError(t, loaded.Load(&buf))
+
+		v, ok := loaded.Get(`b`)
+		assert.True(t, ok)
+		assert.Equal(t, `B`, v.Name)
+	})
+
+	t.Run(`keep expiry`, func(t *testing.T) {
+		c := cache.TLRU[int, int](10, time.Hour)
+		c.AddWithTTL(1, 1, cacheDuration)
+		c.Add(2, 2)
+		c.AddWithTTL(3, 3, time.Nanosecond)
+
+		var buf bytes.Buffer
+		assert.NoError(t, c.Save(&buf))
+
+		loaded := cache.TLRU[int, int](10, time.Hour)
+		assert.NoError(t, loaded.Load(&buf))
+		assertKeys(t, []int{1, 2}, loaded.Keys())
+
+		sleep()
+		_, ok := loaded.Get(1)
+		assert.False(t, ok)
+
+		v, ok := loaded.Get(2)
+		assert.True(t, ok)
+		assert.Equal(t, 2, v)
+	})
+
+	t.Run(`sharded`, func(t *testing.T) {
+		newCache := func() cache.Cache[int, int] {
+			return cache.Sharded(4, func() cache.Cache[int, int] {
+				return cache.TLRU[int, int](10, time.Hour)
+			})
+		}
+
+		c := newCache()
+		for key := range 10 {
+			c.Add(key, key)
+		}
+
+		var buf bytes.Buffer
+		assert.NoError(t, c.Save(&buf))
+
+		loaded := newCache()
+		assert.NoError(t, loaded.Load(&buf))
+		assert.Equal(t, 10, loaded.Len())
+	})
+
+	t.Run(`invalid input`, func(t *testing.T) {
+		c := cache.LRU[int, int](10)
+
+		err := c.Load(strings.NewReader(`not gob`))
+		assert.Error(t, err)
+		assert.Equal(t, 0, c.Len())
+	})
+}
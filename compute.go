@@ -0,0 +1,35 @@
+package cache
+
+// Action tells Compute what to do with the value returned by its function
+type Action int
+
+const (
+	// ActionKeep leaves the cache unchanged
+	ActionKeep Action = iota
+
+	// ActionStore adds the returned value, replacing the old value if there is one
+	ActionStore
+
+	// ActionDelete removes the old value if there is one
+	ActionDelete
+)
+
+// ComputeFunc returns a new value for a key based on its old value, found is false if there is no old value.
+// It runs with the cache locked, so it must not use the cache
+type ComputeFunc[V any] func(old V, found bool) (V, Action)
+
+// CompareAndSwap replaces the value for key with new if the current value is equal to old,
+// and returns true if it did
+func CompareAndSwap[K, V comparable](c Cache[K, V], key K, old, new V) bool {
+	swapped := false
+	c.Compute(key, func(current V, found bool) (V, Action) {
+		if !found || current != old {
+			return current, ActionKeep
+		}
+
+		swapped = true
+		return new, ActionStore
+	})
+
+	return swapped
+}
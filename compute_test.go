@@ -0,0 +1,154 @@
+package cache_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/FallenTaters/cache"
+	"github.com/FallenTaters/cache/assert"
+)
+
+func increment(old int, _ bool) (int, cache.Action) {
+	return old + 1, cache.ActionStore
+}
+
+func TestCompute(t *testing.T) {
+	caches := map[string]func() cache.Cache[int, int]{
+		`fifo`:    func() cache.Cache[int, int] { return cache.FIFO[int, int](10) },
+		`lru`:     func() cache.Cache[int, int] { return cache.LRU[int, int](10) },
+		`tlru`:    func() cache.Cache[int, int] { return cache.TLRU[int, int](10, time.Hour) },
+		`sharded`: newSharded,
+	}
+
+	for name, newCache := range caches {
+		t.Run(name, func(t *testing.T) {
+			c := newCache()
+
+			var wg sync.WaitGroup
+			for i := 0; i < concurrentCount; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					c.Compute(1, increment)
+				}()
+			}
+			wg.Wait()
+
+			v, ok := c.Get(1)
+			assert.True(t, ok)
+			assert.Equal(t, concurrentCount, v)
+
+			v, ok = c.Compute(1, func(old int, found bool) (int, cache.Action) {
+				assert.True(t, found)
+				return 0, cache.ActionKeep
+			})
+			assert.True(t, ok)
+			assert.Equal(t, concurrentCount, v)
+
+			v, ok = c.Compute(1, func(int, bool) (int, cache.Action) {
+				return 0, cache.ActionDelete
+			})
+			assert.False(t, ok)
+			assert.Equal(t, 0, v)
+
+			_, ok = c.Get(1)
+			assert.False(t, ok)
+		})
+	}
+
+	t.Run(`expired values are not found`, func(t *testing.T) {
+		c := cache.TFIFO[int, int](10, cacheDuration)
+		c.Add(1, 1)
+		sleep()
+
+		v, ok := c.Compute(1, func(old int, found bool) (int, cache.Action) {
+			assert.False(t, found)
+			assert.Equal(t, 0, old)
+			return 0, cache.ActionKeep
+		})
+		assert.False(t, ok)
+		assert.Equal(t, 0, v)
+
+		v, ok = c.Compute(1, increment)
+		assert.True(t, ok)
+		assert.Equal(t, 1, v)
+
+		sleep()
+		_, ok = c.Get(1)
+		assert.False(t, ok, `stored values expire after maxAge`)
+	})
+
+	t.Run(`onEvict`, func(t *testing.T) {
+		evictions, onEvict := recordEvictions()
+		c := cache.LRU(10, onEvict)
+
+		c.Compute(1, increment)
+		c.Compute(1, increment)
+		c.Compute(1, func(int, bool) (int, cache.Action) { return 0, cache.ActionDelete })
+
+		assert.Equal(t, 2, len(*evictions))
+		assert.Equal(t, cache.ReasonReplaced, (*evictions)[0].reason)
+		assert.Equal(t, cache.ReasonDeleted, (*evictions)[1].reason)
+	})
+
+	t.Run(`panic does not leave the cache locked`, func(t *testing.T) {
+		c := cache.LRU[int, int](10)
+
+		func() {
+			defer func() {
+				assert.Equal(t, `oops`, recover().(string))
+			}()
+			c.Compute(1, func(int, bool) (int, cache.Action) {
+				panic(`oops`)
+			})
+		}()
+
+		c.Add(1, 1)
+		v, ok := c.Get(1)
+		assert.True(t, ok)
+		assert.Equal(t, 1, v)
+	})
+}
+
+func TestAddIfAbsent(t *testing.T) {
+	for name, c := range map[string]cache.Cache[int, int]{
+		`lru`:  cache.LRU[int, int](10),
+		`tlru`: cache.TLRU[int, int](10, cacheDuration),
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.True(t, c.AddIfAbsent(1, 1))
+			assert.False(t, c.AddIfAbsent(1, 2))
+
+			v, ok := c.Get(1)
+			assert.True(t, ok)
+			assert.Equal(t, 1, v)
+		})
+	}
+
+	t.Run(`replace expired value`, func(t *testing.T) {
+		c := cache.TLRU[int, int](10, cacheDuration)
+		c.Add(1, 1)
+		sleep()
+
+		assert.True(t, c.AddIfAbsent(1, 2))
+
+		v, ok := c.Get(1)
+		assert.True(t, ok)
+		assert.Equal(t, 2, v)
+	})
+}
+
+func TestCompareAndSwap(t *testing.T) {
+	c := cache.FIFO[int, int](10)
+
+	assert.False(t, cache.CompareAndSwap(c, 1, 0, 1), `missing keys are not swapped`)
+
+	c.Add(1, 1)
+	assert.False(t, cache.CompareAndSwap(c, 1, 2, 3))
+	assert.True(t, cache.CompareAndSwap(c, 1, 1, 2))
+
+	v, ok := c.Get(1)
+	assert.True(t, ok)
+	assert.Equal(t, 2, v)
+}
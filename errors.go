@@ -0,0 +1,30 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrMissingKey is returned by GetOrAdd for a key that it waited for while a BatchAddFunc loaded it,
+// if the BatchAddFunc did not return a value for that key
+var ErrMissingKey = errors.New(`cache: key missing from BatchAddFunc result`)
+
+// LoaderPanicError is returned to all callers waiting for an AddFunc that panicked,
+// or whose result panicked while it was added, for example in a Weigher
+type LoaderPanicError struct {
+	// Value is the value passed to panic
+	Value any
+
+	// Stack is the stack trace of the panicking goroutine
+	Stack []byte
+}
+
+func (e *LoaderPanicError) Error() string {
+	return fmt.Sprintf(`cache: addFunc panicked: %v`, e.Value)
+}
+
+// Unwrap returns the panic value if it is an error
+func (e *LoaderPanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
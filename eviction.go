@@ -0,0 +1,62 @@
+package cache
+
+// EvictionReason tells why an entry left the cache
+type EvictionReason int
+
+const (
+	// ReasonCapacity means the entry was evicted to make room for other entries,
+	// or was too large to be added at all
+	ReasonCapacity EvictionReason = iota
+
+	// ReasonExpired means the entry was older than its maximum age
+	ReasonExpired
+
+	// ReasonDeleted means the entry was removed with Delete
+	ReasonDeleted
+
+	// ReasonReplaced means the value was replaced by a new value for the same key
+	ReasonReplaced
+
+	// ReasonCleared means the entry was removed because the whole cache was cleared
+	ReasonCleared
+
+	numReasons
+)
+
+func (r EvictionReason) String() string {
+	switch r {
+	case ReasonCapacity:
+		return `capacity`
+	case ReasonExpired:
+		return `expired`
+	case ReasonDeleted:
+		return `deleted`
+	case ReasonReplaced:
+		return `replaced`
+	case ReasonCleared:
+		return `cleared`
+	default:
+		return `unknown`
+	}
+}
+
+type eviction[K comparable, V any] struct {
+	key    K
+	value  V
+	reason EvictionReason
+}
+
+// evictor is embedded in policies to report entries that leave the cache
+type evictor[K comparable, V any] struct {
+	onEvict func(K, V, EvictionReason)
+}
+
+func (e *evictor[K, V]) setOnEvict(onEvict func(K, V, EvictionReason)) {
+	e.onEvict = onEvict
+}
+
+func (e *evictor[K, V]) evicted(key K, value V, reason EvictionReason) {
+	if e.onEvict != nil {
+		e.onEvict(key, value, reason)
+	}
+}
@@ -0,0 +1,138 @@
+package cache_test
+
+import (
+	"testing"
+
+	"github.com/FallenTaters/cache"
+	"github.com/FallenTaters/cache/assert"
+)
+
+type evicted struct {
+	key    int
+	value  int
+	reason cache.EvictionReason
+}
+
+func recordEvictions() (*[]evicted, cache.Option[int, int]) {
+	var evictions []evicted
+	return &evictions, cache.OnEvict(func(key int, value int, reason cache.EvictionReason) {
+		evictions = append(evictions, evicted{key, value, reason})
+	})
+}
+
+func TestOnEvict(t *testing.T) {
+	t.Run(`capacity`, func(t *testing.T) {
+		evictions, onEvict := recordEvictions()
+		c := cache.LRU(2, onEvict)
+
+		c.Add(1, 1)
+		c.Add(2, 2)
+		c.Add(3, 3)
+
+		assert.Equal(t, 1, len(*evictions))
+		assert.Equal(t, evicted{1, 1, cache.ReasonCapacity}, (*evictions)[0])
+	})
+
+	t.Run(`replaced`, func(t *testing.T) {
+		evictions, onEvict := recordEvictions()
+		c := cache.FIFO(2, onEvict)
+
+		c.Add(1, 1)
+		c.Add(1, 2)
+
+		assert.Equal(t, 1, len(*evictions))
+		assert.Equal(t, evicted{1, 1, cache.ReasonReplaced}, (*evictions)[0])
+	})
+
+	t.Run(`deleted`, func(t *testing.T) {
+		evictions, onEvict := recordEvictions()
+		c := cache.LFU(2, onEvict)
+
+		c.Add(1, 1)
+		c.Delete(1)
+		c.Delete(2)
+
+		assert.Equal(t, 1, len(*evictions))
+		assert.Equal(t, evicted{1, 1, cache.ReasonDeleted}, (*evictions)[0])
+	})
+
+	t.Run(`expired`, func(t *testing.T) {
+		evictions, onEvict := recordEvictions()
+		c := cache.TLRU(2, cacheDuration, onEvict)
+
+		c.Add(1, 1)
+		sleep()
+		_, _ = c.Get(1)
+
+		assert.Equal(t, 1, len(*evictions))
+		assert.Equal(t, evicted{1, 1, cache.ReasonExpired}, (*evictions)[0])
+	})
+
+	t.Run(`expired when evicted for capacity`, func(t *testing.T) {
+		evictions, onEvict := recordEvictions()
+		c := cache.TFIFO(1, cacheDuration, onEvict)
+
+		c.Add(1, 1)
+		sleep()
+		c.Add(2, 2)
+
+		assert.Equal(t, 1, len(*evictions))
+		assert.Equal(t, evicted{1, 1, cache.ReasonExpired}, (*evictions)[0])
+	})
+
+	t.Run(`shared load is added once`, func(t *testing.T) {
+		evictions, onEvict := recordEvictions()
+		c := cache.LRU(2, onEvict)
+
+		loadConcurrently(t, c, 1, 1, 20)
+
+		assert.Equal(t, 0, len(*evictions))
+		assert.Equal(t, uint64(0), c.Stats().Evictions[cache.ReasonReplaced])
+	})
+
+	t.Run(`every policy reports capacity`, func(t *testing.T) {
+		constructors := map[string]func(int, ...cache.Option[int, int]) cache.Cache[int, int]{
+			`fifo`:    cache.FIFO[int, int],
+			`lru`:     cache.LRU[int, int],
+			`lfu`:     cache.LFU[int, int],
+			`tinylfu`: cache.TinyLFU[int, int],
+			`arc`:     cache.ARC[int, int],
+			`sieve`:   cache.SIEVE[int, int],
+			`s3fifo`:  cache.S3FIFO[int, int],
+		}
+
+		for name, constructor := range constructors {
+			evictions, onEvict := recordEvictions()
+			c := constructor(10, onEvict)
+
+			for key := 0; key < 30; key++ {
+				c.Add(key, key)
+			}
+
+			var found int
+			for key := 0; key < 30; key++ {
+				if _, ok := c.Get(key); ok {
+					found++
+				}
+			}
+
+			assert.Equal(t, 30, found+len(*evictions), name)
+			for _, e := range *evictions {
+				assert.Equal(t, cache.ReasonCapacity, e.reason, name)
+				assert.Equal(t, e.key, e.value, name)
+			}
+		}
+	})
+
+	t.Run(`callback may use the cache`, func(t *testing.T) {
+		var c cache.Cache[int, int]
+		c = cache.LRU(1, cache.OnEvict(func(key int, value int, reason cache.EvictionReason) {
+			_, ok := c.Get(key)
+			assert.False(t, ok)
+		}))
+
+		c.Add(1, 1)
+		c.Add(2, 2)
+		c.Delete(2)
+	})
+}
@@ -1,118 +1,130 @@
 package cache
 
-import (
-	"sync"
-
-	"github.com/FallenTaters/cache/list"
-)
+import "github.com/FallenTaters/cache/list"
 
 type keyValue[K comparable, V any] struct {
 	key   K
 	value V
 }
 
-type listValue[K comparable, V any] struct {
-	element *list.Element[keyValue[K, V]]
-	value   V
+type costEntry[K comparable, V any] struct {
+	keyValue[K, V]
+
+	cost int64
 }
 
 type fifo[K comparable, V any] struct {
-	sync.RWMutex
+	evictor[K, V]
 
-	adder addManager[K, V]
+	weigher Weigher[K, V]
+	maxCost int64
+	cost    int64
 
-	maxEntries int
-	entries    *list.List[keyValue[K, V]]
-	values     map[K]listValue[K, V]
+	entries *list.List[costEntry[K, V]]
+	values  map[K]*list.Element[costEntry[K, V]]
 }
 
-func FIFO[K comparable, V any](maxEntries int) Cache[K, V] {
-	return &fifo[K, V]{
-		maxEntries: maxEntries,
-		adder: addManager[K, V]{
-			busyKeys: make(map[K][]chan result[V]),
-		},
-		entries: list.New[keyValue[K, V]](),
-		values:  make(map[K]listValue[K, V], maxEntries),
-	}
+func FIFO[K comparable, V any](maxEntries int, opts ...Option[K, V]) Cache[K, V] {
+	return newFIFO(int64(maxEntries), nil, maxEntries, opts)
 }
 
-func (f *fifo[K, V]) Get(key K) (V, bool) {
-	f.RLock()
-	defer f.RUnlock()
+// WeightedFIFO limits the total cost of all entries to maxCost instead of the number of entries.
+// Entries that cost more than maxCost on their own are not added.
+func WeightedFIFO[K comparable, V any](maxCost int64, weigher Weigher[K, V], opts ...Option[K, V]) Cache[K, V] {
+	return newFIFO(maxCost, weigher, 0, opts)
+}
 
-	v, ok := f.values[key]
-	return v.value, ok
+func newFIFO[K comparable, V any](maxCost int64, weigher Weigher[K, V], size int, opts []Option[K, V]) *base[K, V] {
+	return newBase[K, V](&fifo[K, V]{
+		weigher: weigher,
+		maxCost: maxCost,
+		entries: list.New[costEntry[K, V]](),
+		values:  make(map[K]*list.Element[costEntry[K, V]], size),
+	}, opts)
 }
 
-func (f *fifo[K, V]) Add(key K, value V) {
-	f.Lock()
-	defer f.Unlock()
+func (f *fifo[K, V]) get(key K) (V, bool) {
+	e, ok := f.values[key]
+	if !ok {
+		var empty V
+		return empty, false
+	}
 
-	f.add(key, value)
+	return e.Value.value, true
 }
 
-func (f *fifo[K, V]) GetOrAdd(key K, addFunc AddFunc[V]) (V, error) {
-	f.RLock()
-	v, ok := f.values[key]
-	f.RUnlock()
+func (f *fifo[K, V]) peek(key K) (V, bool) {
+	return f.get(key)
+}
 
-	if ok {
-		return v.value, nil
-	}
+func (f *fifo[K, V]) readOnlyGet() bool {
+	return true
+}
 
-	result := <-f.adder.waitOrAdd(key, addFunc)
-	if result.Err == nil {
-		f.Lock()
-		f.add(key, result.Value)
-		f.Unlock()
+func (f *fifo[K, V]) add(key K, value V) {
+	cost := f.weigher.weigh(key, value)
+	if cost > f.maxCost {
+		if old, ok := f.remove(key); ok {
+			f.evicted(key, old, ReasonReplaced)
+		}
+		f.evicted(key, value, ReasonCapacity)
+		return
 	}
 
-	return result.Value, result.Err
-}
-
-func (f *fifo[K, V]) MustGetOrAdd(key K, addFunc AddFunc[V]) V {
-	v, err := f.GetOrAdd(key, addFunc)
-	if err != nil {
-		panic(err)
+	if e, ok := f.values[key]; ok {
+		f.cost += cost - e.Value.cost
+		f.evicted(key, e.Value.value, ReasonReplaced)
+		e.Value.value = value
+		e.Value.cost = cost
+	} else {
+		f.cost += cost
+		f.values[key] = f.entries.PushFront(costEntry[K, V]{
+			keyValue: keyValue[K, V]{key: key, value: value},
+			cost:     cost,
+		})
 	}
 
-	return v
+	f.trim()
 }
 
-func (f *fifo[K, V]) Delete(key K) {
-	f.Lock()
-	defer f.Unlock()
-
-	v, ok := f.values[key]
+func (f *fifo[K, V]) remove(key K) (V, bool) {
+	e, ok := f.values[key]
 	if !ok {
-		return
+		var empty V
+		return empty, false
 	}
 
-	f.entries.Remove(v.element)
+	f.cost -= e.Value.cost
+	f.entries.Remove(e)
 	delete(f.values, key)
+
+	return e.Value.value, true
 }
 
-func (f *fifo[K, V]) add(key K, value V) {
-	if v, ok := f.values[key]; ok {
-		v.value = value
-		f.values[key] = v
-		return
+func (f *fifo[K, V]) resize(maxEntries int) {
+	f.maxCost = int64(maxEntries)
+	f.trim()
+}
+
+// trim evicts entries from the back until the total cost is no more than maxCost
+func (f *fifo[K, V]) trim() {
+	for f.cost > f.maxCost {
+		back := f.entries.Back().Value.key
+		v, _ := f.remove(back)
+		f.evicted(back, v, ReasonCapacity)
 	}
+}
 
-	if f.entries.Len() == f.maxEntries {
-		back := f.entries.Back()
-		delete(f.values, back.Value.key)
-		f.entries.Remove(back)
+func (f *fifo[K, V]) walk(fn func(K, V)) {
+	for e := f.entries.Back(); e != nil; e = e.Prev() {
+		fn(e.Value.key, e.Value.value)
 	}
+}
 
-	elem := f.entries.PushFront(keyValue[K, V]{
-		key:   key,
-		value: value,
-	})
+func (f *fifo[K, V]) len() int {
+	return len(f.values)
+}
 
-	f.values[key] = listValue[K, V]{
-		element: elem,
-		value:   value,
-	}
+func (f *fifo[K, V]) name() string {
+	return `fifo`
 }
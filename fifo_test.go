@@ -189,3 +189,63 @@ func TestFIFO(t *testing.T) {
 		assert.Equal(t, 1, count)
 	})
 }
+
+func TestWeightedFIFO(t *testing.T) {
+	weigher := func(_ int, v string) int64 {
+		return int64(len(v))
+	}
+
+	t.Run(`evict until cost fits`, func(t *testing.T) {
+		c := cache.WeightedFIFO(10, weigher)
+
+		c.Add(1, `aaaa`)
+		c.Add(2, `bbbb`)
+		c.Add(3, `cccccccc`)
+
+		_, ok := c.Get(1)
+		assert.False(t, ok)
+
+		_, ok = c.Get(2)
+		assert.False(t, ok)
+
+		v, ok := c.Get(3)
+		assert.True(t, ok)
+		assert.Equal(t, `cccccccc`, v)
+	})
+
+	t.Run(`reject entries larger than maxCost`, func(t *testing.T) {
+		c := cache.WeightedFIFO(10, weigher)
+
+		c.Add(1, `aaaa`)
+
+		v, err := c.GetOrAdd(2, func() (string, error) {
+			return `bbbbbbbbbbbb`, nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, `bbbbbbbbbbbb`, v)
+
+		_, ok := c.Get(2)
+		assert.False(t, ok)
+
+		_, ok = c.Get(1)
+		assert.True(t, ok)
+
+		c.Add(1, `aaaaaaaaaaaa`)
+		_, ok = c.Get(1)
+		assert.False(t, ok)
+	})
+
+	t.Run(`replacing updates cost`, func(t *testing.T) {
+		c := cache.WeightedFIFO(10, weigher)
+
+		c.Add(1, `aaaaaaaa`)
+		c.Add(1, `a`)
+		c.Add(2, `bbbbbbbbb`)
+
+		_, ok := c.Get(1)
+		assert.True(t, ok)
+
+		_, ok = c.Get(2)
+		assert.True(t, ok)
+	})
+}
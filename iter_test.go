@@ -0,0 +1,119 @@
+package cache_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/FallenTaters/cache"
+	"github.com/FallenTaters/cache/assert"
+)
+
+func assertKeys(t *testing.T, expected []int, keys []int) {
+	t.Helper()
+
+	assert.Equal(t, len(expected), len(keys))
+	for i := range min(len(expected), len(keys)) {
+		assert.Equal(t, expected[i], keys[i])
+	}
+}
+
+func TestIteration(t *testing.T) {
+	t.Run(`keys in eviction order`, func(t *testing.T) {
+		fifo := cache.FIFO[int, int](10)
+		lru := cache.LRU[int, int](10)
+		for _, c := range []cache.Cache[int, int]{fifo, lru} {
+			c.Add(1, 1)
+			c.Add(2, 2)
+			c.Add(3, 3)
+			c.Get(1)
+		}
+
+		assertKeys(t, []int{1, 2, 3}, fifo.Keys())
+		assertKeys(t, []int{2, 3, 1}, lru.Keys())
+	})
+
+	t.Run(`peek does not count as use`, func(t *testing.T) {
+		c := cache.LRU[int, int](2)
+		c.Add(1, 1)
+		c.Add(2, 2)
+
+		v, ok := c.Peek(1)
+		assert.True(t, ok)
+		assert.Equal(t, 1, v)
+
+		_, ok = c.Peek(3)
+		assert.False(t, ok)
+
+		c.Add(3, 3)
+		_, ok = c.Peek(1)
+		assert.False(t, ok)
+
+		s := c.Stats()
+		assert.Equal(t, uint64(0), s.Hits)
+		assert.Equal(t, uint64(0), s.Misses)
+	})
+
+	t.Run(`all is a snapshot`, func(t *testing.T) {
+		c := cache.LRU[int, int](10)
+		c.Add(1, 1)
+		c.Add(2, 2)
+		c.Add(3, 3)
+
+		var keys []int
+		for key, value := range c.All() {
+			assert.Equal(t, key, value)
+			keys = append(keys, key)
+			c.Delete(key)
+			c.Add(key+10, key+10)
+		}
+		assertKeys(t, []int{1, 2, 3}, keys)
+		assert.Equal(t, 3, c.Len())
+
+		keys = nil
+		for key := range c.All() {
+			keys = append(keys, key)
+			break
+		}
+		assertKeys(t, []int{11}, keys)
+	})
+
+	t.Run(`len`, func(t *testing.T) {
+		for _, c := range []cache.Cache[int, int]{
+			cache.LFU[int, int](10),
+			cache.TinyLFU[int, int](10),
+			cache.ARC[int, int](10),
+			cache.SIEVE[int, int](10),
+			cache.S3FIFO[int, int](10),
+			cache.Sharded(4, func() cache.Cache[int, int] { return cache.LRU[int, int](10) }),
+		} {
+			for key := range 5 {
+				c.Add(key, key)
+			}
+			c.Delete(0)
+
+			assert.Equal(t, 4, c.Len(), c.Policy())
+			assert.Equal(t, 4, len(c.Keys()), c.Policy())
+		}
+	})
+
+	t.Run(`expired entries are left out`, func(t *testing.T) {
+		c := cache.TLRU[int, int](10, cacheDuration)
+		c.Add(1, 1)
+		c.AddWithTTL(2, 2, time.Hour)
+		sleep()
+
+		_, ok := c.Peek(1)
+		assert.False(t, ok)
+
+		v, ok := c.Peek(2)
+		assert.True(t, ok)
+		assert.Equal(t, 2, v)
+
+		assert.Equal(t, 1, c.Len())
+		assertKeys(t, []int{2}, c.Keys())
+		for key, value := range c.All() {
+			assert.Equal(t, 2, key)
+			assert.Equal(t, 2, value)
+		}
+	})
+}
@@ -0,0 +1,45 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+type janitor struct {
+	stop chan struct{}
+	once sync.Once
+}
+
+// startJanitor runs clean every interval until the janitor is closed
+func startJanitor(interval time.Duration, clean func()) *janitor {
+	j := &janitor{
+		stop: make(chan struct{}),
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				clean()
+			case <-j.stop:
+				return
+			}
+		}
+	}()
+
+	return j
+}
+
+// Close stops the janitor, it is safe to call on a nil janitor or multiple times
+func (j *janitor) Close() {
+	if j == nil {
+		return
+	}
+
+	j.once.Do(func() {
+		close(j.stop)
+	})
+}
@@ -1,6 +1,8 @@
 package cache
 
 import (
+	"context"
+	"runtime/debug"
 	"sync"
 )
 
@@ -9,40 +11,246 @@ type result[V any] struct {
 	Err   error
 }
 
+// call is a running addFunc, shared by all callers waiting for the same key
+type call[V any] struct {
+	done   chan struct{}
+	result result[V]
+
+	waiters int
+	cancel  context.CancelFunc
+}
+
 type addManager[K comparable, V any] struct {
-	sync.RWMutex
+	sync.Mutex
+
+	busyKeys map[K]*call[V]
+}
+
+// wait returns the result of addFunc for key, sharing an addFunc that is already running for the same key.
+// If ctx is done first, wait returns ctx.Err(). The context passed to addFunc is only cancelled
+// once every waiter has gone away. If wait started addFunc, done is called once with the result,
+// before any waiter returns.
+func (km *addManager[K, V]) wait(ctx context.Context, key K, addFunc AddFuncCtx[V], done func(result[V])) result[V] {
+	km.Lock()
+	c, ok := km.busyKeys[key]
+	if !ok {
+		c = km.start(key, addFunc, done)
+	}
+	c.waiters++
+	km.Unlock()
+
+	return km.await(ctx, key, c)
+}
+
+// waitMany is like wait for many keys, but runs a single addFunc for all keys that are not busy yet.
+// done is called once with the values that addFunc returned for those keys.
+// If claimed is not nil, it gets the keys passed to addFunc before waitMany waits for anything
+func (km *addManager[K, V]) waitMany(ctx context.Context, keys []K, addFunc batchAddFuncCtx[K, V], done func(map[K]V), claimed func([]K)) map[K]result[V] {
+	calls := make(map[K]*call[V], len(keys))
+	var missing []K
+
+	km.Lock()
+	for _, key := range keys {
+		if _, ok := calls[key]; ok {
+			continue
+		}
+
+		c, ok := km.busyKeys[key]
+		if !ok {
+			// the call is added by startBatch
+			calls[key] = nil
+			missing = append(missing, key)
+			continue
+		}
+		c.waiters++
+		calls[key] = c
+	}
+
+	if len(missing) > 0 {
+		for key, c := range km.startBatch(missing, addFunc, done) {
+			c.waiters++
+			calls[key] = c
+		}
+	}
+	km.Unlock()
+
+	if claimed != nil {
+		claimed(missing)
+	}
+
+	results := make(map[K]result[V], len(calls))
+	for key, c := range calls {
+		results[key] = km.await(ctx, key, c)
+	}
 
-	busyKeys map[K][]chan result[V]
+	return results
 }
 
-func (km *addManager[K, V]) waitOrAdd(key K, addFunc AddFunc[V]) <-chan result[V] {
+// await returns the result of c, or ctx.Err() if ctx is done first
+func (km *addManager[K, V]) await(ctx context.Context, key K, c *call[V]) result[V] {
+	select {
+	case <-c.done:
+		return c.result
+	case <-ctx.Done():
+	}
+
+	km.Lock()
+	c.waiters--
+	if c.waiters == 0 {
+		c.cancel()
+		km.release(key, c)
+	}
+	km.Unlock()
+
+	var empty V
+	return result[V]{empty, ctx.Err()}
+}
+
+// refresh runs addFunc for key in the background, unless an addFunc is already running for key.
+// If refresh started addFunc, done is called with the result.
+func (km *addManager[K, V]) refresh(key K, addFunc AddFuncCtx[V], done func(result[V])) {
 	km.Lock()
 	defer km.Unlock()
 
-	ch := make(chan result[V])
+	if _, ok := km.busyKeys[key]; ok {
+		return
+	}
+
+	// the refresh counts as a waiter, so addFunc is not cancelled when other waiters go away
+	c := km.start(key, addFunc, done)
+	c.waiters++
+}
+
+// start runs addFunc for key in a new goroutine and passes the result to done, km must be locked
+func (km *addManager[K, V]) start(key K, addFunc AddFuncCtx[V], done func(result[V])) *call[V] {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &call[V]{
+		done:   make(chan struct{}),
+		cancel: cancel,
+	}
+	km.busyKeys[key] = c
+
+	go func() {
+		defer cancel()
+		defer func() {
+			km.Lock()
+			km.release(key, c)
+			km.Unlock()
+
+			close(c.done)
+		}()
+
+		v, err := callAddFunc(ctx, addFunc)
+		c.result = result[V]{v, err}
+		if err := callDone(done, c.result); err != nil {
+			c.result = result[V]{Err: err}
+		}
+	}()
+
+	return c
+}
 
-	channels, ok := km.busyKeys[key]
-	if ok {
-		// wait for existing addFunc
-		km.busyKeys[key] = append(channels, ch)
-		return ch
+// startBatch runs addFunc for keys in a new goroutine, with a call for every key, km must be locked.
+// The context passed to addFunc is only cancelled once every key has lost all its waiters.
+// done is called with the values for keys, unless addFunc failed
+func (km *addManager[K, V]) startBatch(keys []K, addFunc batchAddFuncCtx[K, V], done func(map[K]V)) map[K]*call[V] {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// remaining is the number of calls that still have waiters, it is guarded by km
+	remaining := len(keys)
+	calls := make(map[K]*call[V], len(keys))
+	for _, key := range keys {
+		c := &call[V]{
+			done: make(chan struct{}),
+			cancel: func() {
+				remaining--
+				if remaining == 0 {
+					cancel()
+				}
+			},
+		}
+		km.busyKeys[key] = c
+		calls[key] = c
 	}
 
-	// start a new addFunc
-	km.busyKeys[key] = []chan result[V]{ch}
 	go func() {
-		v, err := addFunc()
-		result := result[V]{v, err}
+		defer cancel()
+		defer func() {
+			km.Lock()
+			for key, c := range calls {
+				km.release(key, c)
+			}
+			km.Unlock()
+
+			for _, c := range calls {
+				close(c.done)
+			}
+		}()
+
+		values, err := callAddFunc(ctx, func(ctx context.Context) (map[K]V, error) {
+			return addFunc(ctx, keys)
+		})
+		if err == nil {
+			// values may contain keys that were not asked for
+			found := make(map[K]V, len(keys))
+			for _, key := range keys {
+				if v, ok := values[key]; ok {
+					found[key] = v
+				}
+			}
+			err = callDone(done, found)
+		}
 
-		km.Lock()
-		defer km.Unlock()
-		for _, ch := range km.busyKeys[key] {
-			ch <- result
-			close(ch)
+		for key, c := range calls {
+			v, ok := values[key]
+			switch {
+			case err != nil:
+				c.result.Err = err
+			case !ok:
+				c.result.Err = ErrMissingKey
+			default:
+				c.result.Value = v
+			}
 		}
+	}()
+
+	return calls
+}
 
+// release removes c from busyKeys unless a newer call has replaced it, km must be locked
+func (km *addManager[K, V]) release(key K, c *call[V]) {
+	if km.busyKeys[key] == c {
 		delete(km.busyKeys, key)
+	}
+}
+
+// callAddFunc runs addFunc, turning a panic into a *LoaderPanicError
+func callAddFunc[V any](ctx context.Context, addFunc AddFuncCtx[V]) (v V, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = loaderPanic(r)
+		}
 	}()
 
-	return ch
+	return addFunc(ctx)
+}
+
+// callDone passes the result of an addFunc to done, turning a panic into a *LoaderPanicError.
+// done adds the result to the cache, which can panic in a Weigher or OnEvict
+func callDone[T any](done func(T), v T) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = loaderPanic(r)
+		}
+	}()
+
+	done(v)
+	return nil
+}
+
+func loaderPanic(r any) *LoaderPanicError {
+	return &LoaderPanicError{
+		Value: r,
+		Stack: debug.Stack(),
+	}
 }
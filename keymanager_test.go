@@ -0,0 +1,195 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/FallenTaters/cache"
+	"github.com/FallenTaters/cache/assert"
+)
+
+func TestGetOrAddCtx(t *testing.T) {
+	t.Run(`cancelled waiter returns immediately`, func(t *testing.T) {
+		c := newLRU()
+
+		release := make(chan struct{})
+		defer close(release)
+
+		ctx, cancel := context.WithTimeout(context.Background(), cacheDuration)
+		defer cancel()
+
+		_, err := c.GetOrAddCtx(ctx, 1, func(ctx context.Context) (int, error) {
+			<-release
+			return 1, nil
+		})
+		assert.ErrorIs(t, context.DeadlineExceeded, err)
+
+		_, ok := c.Get(1)
+		assert.False(t, ok)
+	})
+
+	t.Run(`loader keeps running while others wait`, func(t *testing.T) {
+		c := newFIFO()
+
+		started := make(chan struct{})
+		loaderErr := make(chan error, 1)
+		addFunc := func(ctx context.Context) (int, error) {
+			close(started)
+			select {
+			case <-time.After(2 * cacheDuration):
+				loaderErr <- nil
+				return 1, nil
+			case <-ctx.Done():
+				loaderErr <- ctx.Err()
+				return 0, ctx.Err()
+			}
+		}
+
+		done := make(chan int)
+		go func() {
+			v, err := c.GetOrAddCtx(context.Background(), 1, addFunc)
+			assert.NoError(t, err)
+			done <- v
+		}()
+		<-started
+
+		ctx, cancel := context.WithTimeout(context.Background(), cacheDuration/2)
+		defer cancel()
+
+		_, err := c.GetOrAddCtx(ctx, 1, addFunc)
+		assert.ErrorIs(t, context.DeadlineExceeded, err)
+
+		assert.Equal(t, 1, <-done)
+		assert.NoError(t, <-loaderErr)
+	})
+
+	t.Run(`loader cancelled when all waiters are gone`, func(t *testing.T) {
+		c := newTLRU()
+
+		loaderErr := make(chan error, 1)
+		ctx, cancel := context.WithCancel(context.Background())
+
+		go func() {
+			time.Sleep(cacheDuration)
+			cancel()
+		}()
+
+		_, err := c.GetOrAddCtx(ctx, 1, func(ctx context.Context) (int, error) {
+			<-ctx.Done()
+			loaderErr <- ctx.Err()
+			return 0, ctx.Err()
+		})
+		assert.ErrorIs(t, context.Canceled, err)
+		assert.ErrorIs(t, context.Canceled, <-loaderErr)
+
+		v, err := c.GetOrAddCtx(context.Background(), 1, func(context.Context) (int, error) {
+			return 2, nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 2, v)
+	})
+}
+
+// loadConcurrently calls GetOrAdd for key from n goroutines that share a single slow load
+func loadConcurrently(t *testing.T, c cache.Cache[int, int], key, value, n int) {
+	var wg sync.WaitGroup
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func() {
+			defer wg.Done()
+
+			v, err := c.GetOrAdd(key, func() (int, error) {
+				time.Sleep(cacheDuration)
+				return value, nil
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, value, v)
+		}()
+	}
+	wg.Wait()
+}
+
+func TestLoaderPanic(t *testing.T) {
+	t.Run(`panic is returned as error`, func(t *testing.T) {
+		c := newLRU()
+
+		_, err := c.GetOrAdd(1, func() (int, error) {
+			panic(`oops`)
+		})
+
+		var panicErr *cache.LoaderPanicError
+		assert.ErrorAs(t, &panicErr, err)
+		assert.Equal(t, `oops`, panicErr.Value.(string))
+		assert.True(t, len(panicErr.Stack) > 0)
+
+		_, ok := c.Get(1)
+		assert.False(t, ok)
+	})
+
+	t.Run(`panic with error is unwrapped`, func(t *testing.T) {
+		myErr := errors.New(`myErr`)
+		c := newFIFO()
+
+		_, err := c.GetOrAdd(1, func() (int, error) {
+			panic(myErr)
+		})
+		assert.ErrorIs(t, myErr, err)
+	})
+
+	t.Run(`all waiters get the panic and the key is released`, func(t *testing.T) {
+		c := newTFIFO()
+
+		var wg sync.WaitGroup
+		wg.Add(10)
+		for i := 0; i < 10; i++ {
+			go func() {
+				defer wg.Done()
+
+				_, err := c.GetOrAdd(1, func() (int, error) {
+					time.Sleep(cacheDuration)
+					panic(`oops`)
+				})
+
+				var panicErr *cache.LoaderPanicError
+				assert.ErrorAs(t, &panicErr, err)
+			}()
+		}
+		wg.Wait()
+
+		v, err := c.GetOrAdd(1, newAddFunc(1, nil))
+		assert.NoError(t, err)
+		assert.Equal(t, 1, v)
+	})
+
+	t.Run(`panic while adding the result is returned and the key is released`, func(t *testing.T) {
+		c := cache.WeightedLRU[int, int](10, func(_, v int) int64 {
+			if v < 0 {
+				panic(`negative`)
+			}
+			return int64(v)
+		})
+
+		var panicErr *cache.LoaderPanicError
+		_, err := c.GetOrAdd(1, newAddFunc(-1, nil))
+		assert.ErrorAs(t, &panicErr, err)
+		assert.Equal(t, `negative`, panicErr.Value.(string))
+
+		_, err = c.GetOrAddMany([]int{2}, func([]int) (map[int]int, error) {
+			return map[int]int{2: -1}, nil
+		})
+		assert.ErrorAs(t, &panicErr, err)
+
+		v, err := c.GetOrAdd(1, newAddFunc(1, nil))
+		assert.NoError(t, err)
+		assert.Equal(t, 1, v)
+
+		values, err := c.GetOrAddMany([]int{2}, func([]int) (map[int]int, error) {
+			return map[int]int{2: 2}, nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 2, values[2])
+	})
+}
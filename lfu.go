@@ -0,0 +1,168 @@
+package cache
+
+import "github.com/FallenTaters/cache/list"
+
+// lfuBucket holds all entries that have been accessed freq times, most recently used first
+type lfuBucket[K comparable, V any] struct {
+	freq    int
+	entries *list.List[lfuEntry[K, V]]
+}
+
+type lfuEntry[K comparable, V any] struct {
+	keyValue[K, V]
+
+	bucket *list.Element[*lfuBucket[K, V]]
+}
+
+type lfu[K comparable, V any] struct {
+	evictor[K, V]
+
+	maxEntries int
+
+	// buckets is ordered by frequency, lowest first
+	buckets *list.List[*lfuBucket[K, V]]
+	values  map[K]*list.Element[lfuEntry[K, V]]
+}
+
+func LFU[K comparable, V any](maxEntries int, opts ...Option[K, V]) Cache[K, V] {
+	return newBase[K, V](&lfu[K, V]{
+		maxEntries: maxEntries,
+		buckets:    list.New[*lfuBucket[K, V]](),
+		values:     make(map[K]*list.Element[lfuEntry[K, V]], maxEntries),
+	}, opts)
+}
+
+func (l *lfu[K, V]) get(key K) (V, bool) {
+	e, ok := l.values[key]
+	if !ok {
+		var empty V
+		return empty, false
+	}
+
+	l.increment(e)
+
+	return e.Value.value, true
+}
+
+func (l *lfu[K, V]) peek(key K) (V, bool) {
+	e, ok := l.values[key]
+	if !ok {
+		var empty V
+		return empty, false
+	}
+
+	return e.Value.value, true
+}
+
+func (l *lfu[K, V]) readOnlyGet() bool {
+	return false
+}
+
+func (l *lfu[K, V]) add(key K, value V) {
+	if e, ok := l.values[key]; ok {
+		l.evicted(key, e.Value.value, ReasonReplaced)
+		e.Value.value = value
+		l.increment(e)
+		return
+	}
+
+	if l.maxEntries <= 0 {
+		l.evicted(key, value, ReasonCapacity)
+		return
+	}
+
+	if len(l.values) >= l.maxEntries {
+		l.evict()
+	}
+
+	front := l.buckets.Front()
+	if front == nil || front.Value.freq != 1 {
+		front = l.buckets.PushFront(&lfuBucket[K, V]{
+			freq:    1,
+			entries: list.New[lfuEntry[K, V]](),
+		})
+	}
+
+	l.values[key] = front.Value.entries.PushFront(lfuEntry[K, V]{
+		keyValue: keyValue[K, V]{key: key, value: value},
+		bucket:   front,
+	})
+}
+
+func (l *lfu[K, V]) remove(key K) (V, bool) {
+	e, ok := l.values[key]
+	if !ok {
+		var empty V
+		return empty, false
+	}
+
+	l.unlink(e)
+	delete(l.values, key)
+
+	return e.Value.value, true
+}
+
+func (l *lfu[K, V]) resize(maxEntries int) {
+	l.maxEntries = maxEntries
+	for len(l.values) > l.maxEntries {
+		l.evict()
+	}
+}
+
+func (l *lfu[K, V]) walk(fn func(K, V)) {
+	for b := l.buckets.Front(); b != nil; b = b.Next() {
+		for e := b.Value.entries.Back(); e != nil; e = e.Prev() {
+			fn(e.Value.key, e.Value.value)
+		}
+	}
+}
+
+func (l *lfu[K, V]) len() int {
+	return len(l.values)
+}
+
+func (l *lfu[K, V]) name() string {
+	return `lfu`
+}
+
+// evict removes the least recently used entry of the lowest frequency
+func (l *lfu[K, V]) evict() {
+	front := l.buckets.Front()
+	if front == nil {
+		return
+	}
+
+	back := front.Value.entries.Back()
+	delete(l.values, back.Value.key)
+	l.unlink(back)
+	l.evicted(back.Value.key, back.Value.value, ReasonCapacity)
+}
+
+// increment moves e to the bucket for the next frequency
+func (l *lfu[K, V]) increment(e *list.Element[lfuEntry[K, V]]) {
+	bucket := e.Value.bucket
+
+	next := bucket.Next()
+	if next == nil || next.Value.freq != bucket.Value.freq+1 {
+		next = l.buckets.InsertAfter(&lfuBucket[K, V]{
+			freq:    bucket.Value.freq + 1,
+			entries: list.New[lfuEntry[K, V]](),
+		}, bucket)
+	}
+
+	entry := e.Value
+	l.unlink(e)
+
+	entry.bucket = next
+	l.values[entry.key] = next.Value.entries.PushFront(entry)
+}
+
+// unlink removes e from its bucket, and the bucket if it is now empty
+func (l *lfu[K, V]) unlink(e *list.Element[lfuEntry[K, V]]) {
+	bucket := e.Value.bucket
+
+	bucket.Value.entries.Remove(e)
+	if bucket.Value.entries.Len() == 0 {
+		l.buckets.Remove(bucket)
+	}
+}
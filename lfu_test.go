@@ -0,0 +1,180 @@
+package cache_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/FallenTaters/cache"
+	"github.com/FallenTaters/cache/assert"
+)
+
+func newLFU() cache.Cache[int, int] {
+	return cache.LFU[int, int](2)
+}
+
+func TestLFU(t *testing.T) {
+	t.Run(`get non-existing`, func(t *testing.T) {
+		c := newLFU()
+
+		v, ok := c.Get(1)
+		assert.False(t, ok)
+		assert.Equal(t, 0, v)
+	})
+
+	t.Run(`add and get`, func(t *testing.T) {
+		c := newLFU()
+
+		c.Add(1, 1)
+
+		v, ok := c.Get(1)
+		assert.True(t, ok)
+		assert.Equal(t, 1, v)
+	})
+
+	t.Run(`least frequently used out first`, func(t *testing.T) {
+		c := newLFU()
+
+		_ = c.MustGetOrAdd(1, newAddFunc(1, nil))
+		_ = c.MustGetOrAdd(2, newAddFunc(2, nil))
+		_, _ = c.Get(1)
+		_, _ = c.Get(1)
+		_, _ = c.Get(2)
+		_ = c.MustGetOrAdd(3, newAddFunc(3, nil))
+
+		v, ok := c.Get(1)
+		assert.Equal(t, 1, v)
+		assert.True(t, ok)
+
+		v, ok = c.Get(2)
+		assert.Equal(t, 0, v)
+		assert.False(t, ok)
+
+		v, ok = c.Get(3)
+		assert.Equal(t, 3, v)
+		assert.True(t, ok)
+	})
+
+	t.Run(`shared load counts as one use`, func(t *testing.T) {
+		c := newLFU()
+
+		loadConcurrently(t, c, 1, 1, 20)
+		c.Add(2, 2)
+		_, _ = c.Get(2)
+		c.Add(3, 3)
+
+		_, ok := c.Get(1)
+		assert.False(t, ok)
+
+		v, ok := c.Get(2)
+		assert.Equal(t, 2, v)
+		assert.True(t, ok)
+	})
+
+	t.Run(`least recently used out first for equal frequency`, func(t *testing.T) {
+		c := newLFU()
+
+		c.Add(1, 1)
+		c.Add(2, 2)
+		c.Add(3, 3)
+
+		_, ok := c.Get(1)
+		assert.False(t, ok)
+
+		_, ok = c.Get(2)
+		assert.True(t, ok)
+
+		_, ok = c.Get(3)
+		assert.True(t, ok)
+	})
+
+	t.Run(`add counts as use`, func(t *testing.T) {
+		c := newLFU()
+
+		c.Add(1, 1)
+		c.Add(2, 2)
+		c.Add(1, 10)
+		c.Add(3, 3)
+
+		v, ok := c.Get(1)
+		assert.Equal(t, 10, v)
+		assert.True(t, ok)
+
+		_, ok = c.Get(2)
+		assert.False(t, ok)
+	})
+
+	t.Run(`return error`, func(t *testing.T) {
+		myErr := errors.New(`myErr`)
+		c := newLFU()
+
+		_, err := c.GetOrAdd(1, newAddFunc(0, myErr))
+		assert.ErrorIs(t, myErr, err)
+	})
+
+	t.Run(`only call addFunc when necessary`, func(t *testing.T) {
+		c := newLFU()
+
+		addFunc, called := calledAddFunc(1, nil)
+
+		c.MustGetOrAdd(1, addFunc)
+		assert.True(t, *called)
+
+		*called = false
+		c.MustGetOrAdd(1, addFunc)
+		assert.False(t, *called)
+	})
+
+	t.Run(`delete`, func(t *testing.T) {
+		c := newLFU()
+
+		_ = c.MustGetOrAdd(1, newAddFunc(1, nil))
+		_, _ = c.Get(1)
+
+		c.Delete(1)
+
+		v, ok := c.Get(1)
+		assert.False(t, ok)
+		assert.Equal(t, 0, v)
+
+		c.Add(2, 2)
+		c.Add(3, 3)
+		c.Add(4, 4)
+
+		_, ok = c.Get(2)
+		assert.False(t, ok)
+	})
+
+	t.Run(`concurrent operations`, func(t *testing.T) {
+		c := newLFU()
+
+		var wg sync.WaitGroup
+
+		wg.Add(concurrentCount)
+		go func() {
+			for i := 0; i < concurrentCount; i++ {
+				expected := i
+				go func() {
+					actual, err := c.GetOrAdd(expected, newAddFunc(expected, nil))
+					assert.Equal(t, expected, actual)
+					assert.NoError(t, err)
+
+					wg.Done()
+				}()
+			}
+		}()
+
+		wg.Add(concurrentCount)
+		go func() {
+			for i := concurrentCount - 1; i >= 0; i-- {
+				expected := i
+				go func() {
+					c.Delete(expected)
+					wg.Done()
+				}()
+			}
+		}()
+
+		wg.Wait()
+	})
+}
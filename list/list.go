@@ -20,21 +20,21 @@ type Element[T any] struct {
 	Value T
 }
 
-// // Next returns the next list element or nil.
-// func (e *Element[T]) Next() *Element[T] {
-// 	if p := e.next; e.list != nil && p != &e.list.root {
-// 		return p
-// 	}
-// 	return nil
-// }
+// Next returns the next list element or nil.
+func (e *Element[T]) Next() *Element[T] {
+	if p := e.next; e.list != nil && p != &e.list.root {
+		return p
+	}
+	return nil
+}
 
-// // Prev returns the previous list element or nil.
-// func (e *Element[T]) Prev() *Element[T] {
-// 	if p := e.prev; e.list != nil && p != &e.list.root {
-// 		return p
-// 	}
-// 	return nil
-// }
+// Prev returns the previous list element or nil.
+func (e *Element[T]) Prev() *Element[T] {
+	if p := e.prev; e.list != nil && p != &e.list.root {
+		return p
+	}
+	return nil
+}
 
 // List represents a doubly linked list.
 // The zero value for List is an empty list ready to use.
@@ -58,13 +58,13 @@ func New[T any]() *List[T] { return new(List[T]).Init() }
 // The complexity is O(1).
 func (l *List[T]) Len() int { return l.len }
 
-// // Front returns the first element of list l or nil if the list is empty.
-// func (l *List[T]) Front() *Element[T] {
-// 	if l.len == 0 {
-// 		return nil
-// 	}
-// 	return l.root.next
-// }
+// Front returns the first element of list l or nil if the list is empty.
+func (l *List[T]) Front() *Element[T] {
+	if l.len == 0 {
+		return nil
+	}
+	return l.root.next
+}
 
 // Back returns the last element of list l or nil if the list is empty.
 func (l *List[T]) Back() *Element[T] {
@@ -156,16 +156,16 @@ func (l *List[T]) PushFront(v T) *Element[T] {
 // 	return l.insertValue(v, mark.prev)
 // }
 
-// // InsertAfter inserts a new element e with value v immediately after mark and returns e.
-// // If mark is not an element of l, the list is not modified.
-// // The mark must not be nil.
-// func (l *List[T]) InsertAfter(v T, mark *Element[T]) *Element[T] {
-// 	if mark.list != l {
-// 		return nil
-// 	}
-// 	// see comment in List.Remove about initialization of l
-// 	return l.insertValue(v, mark)
-// }
+// InsertAfter inserts a new element e with value v immediately after mark and returns e.
+// If mark is not an element of l, the list is not modified.
+// The mark must not be nil.
+func (l *List[T]) InsertAfter(v T, mark *Element[T]) *Element[T] {
+	if mark.list != l {
+		return nil
+	}
+	// see comment in List.Remove about initialization of l
+	return l.insertValue(v, mark)
+}
 
 // MoveToFront moves element e to the front of list l.
 // If e is not an element of l, the list is not modified.
@@ -1,113 +1,128 @@
 package cache
 
-import (
-	"sync"
-
-	"github.com/FallenTaters/cache/list"
-)
+import "github.com/FallenTaters/cache/list"
 
 type lru[K comparable, V any] struct {
-	sync.RWMutex
+	evictor[K, V]
 
-	adder addManager[K, V]
+	weigher Weigher[K, V]
+	maxCost int64
+	cost    int64
 
-	maxEntries int
-	entries    *list.List[keyValue[K, V]]
-	values     map[K]listValue[K, V]
+	entries *list.List[costEntry[K, V]]
+	values  map[K]*list.Element[costEntry[K, V]]
 }
 
-func LRU[K comparable, V any](maxEntries int) Cache[K, V] {
-	return &lru[K, V]{
-		maxEntries: maxEntries,
-		adder: addManager[K, V]{
-			busyKeys: make(map[K][]chan result[V]),
-		},
-		entries: list.New[keyValue[K, V]](),
-		values:  make(map[K]listValue[K, V], maxEntries),
-	}
+func LRU[K comparable, V any](maxEntries int, opts ...Option[K, V]) Cache[K, V] {
+	return newLRU(int64(maxEntries), nil, maxEntries, opts)
 }
 
-func (l *lru[K, V]) Get(key K) (V, bool) {
-	l.Lock()
-	defer l.Unlock()
+// WeightedLRU limits the total cost of all entries to maxCost instead of the number of entries.
+// Entries that cost more than maxCost on their own are not added.
+func WeightedLRU[K comparable, V any](maxCost int64, weigher Weigher[K, V], opts ...Option[K, V]) Cache[K, V] {
+	return newLRU(maxCost, weigher, 0, opts)
+}
 
-	v, ok := l.values[key]
+func newLRU[K comparable, V any](maxCost int64, weigher Weigher[K, V], size int, opts []Option[K, V]) *base[K, V] {
+	return newBase[K, V](&lru[K, V]{
+		weigher: weigher,
+		maxCost: maxCost,
+		entries: list.New[costEntry[K, V]](),
+		values:  make(map[K]*list.Element[costEntry[K, V]], size),
+	}, opts)
+}
+
+func (l *lru[K, V]) get(key K) (V, bool) {
+	e, ok := l.values[key]
 	if !ok {
-		return v.value, false
+		var empty V
+		return empty, false
 	}
 
-	l.entries.MoveToFront(v.element)
+	l.entries.MoveToFront(e)
 
-	return v.value, true
+	return e.Value.value, true
 }
 
-func (l *lru[K, V]) Add(key K, value V) {
-	l.Lock()
-	defer l.Unlock()
+func (l *lru[K, V]) peek(key K) (V, bool) {
+	e, ok := l.values[key]
+	if !ok {
+		var empty V
+		return empty, false
+	}
 
-	l.add(key, value)
+	return e.Value.value, true
 }
 
-func (l *lru[K, V]) GetOrAdd(key K, addFunc AddFunc[V]) (V, error) {
-	v, ok := l.Get(key)
+func (l *lru[K, V]) readOnlyGet() bool {
+	return false
+}
 
-	if ok {
-		return v, nil
+func (l *lru[K, V]) add(key K, value V) {
+	cost := l.weigher.weigh(key, value)
+	if cost > l.maxCost {
+		if old, ok := l.remove(key); ok {
+			l.evicted(key, old, ReasonReplaced)
+		}
+		l.evicted(key, value, ReasonCapacity)
+		return
 	}
 
-	result := <-l.adder.waitOrAdd(key, addFunc)
-	if result.Err == nil {
-		l.Lock()
-		l.add(key, result.Value)
-		l.Unlock()
+	if e, ok := l.values[key]; ok {
+		l.entries.MoveToFront(e)
+		l.cost += cost - e.Value.cost
+		l.evicted(key, e.Value.value, ReasonReplaced)
+		e.Value.value = value
+		e.Value.cost = cost
+	} else {
+		l.cost += cost
+		l.values[key] = l.entries.PushFront(costEntry[K, V]{
+			keyValue: keyValue[K, V]{key: key, value: value},
+			cost:     cost,
+		})
 	}
 
-	return result.Value, result.Err
+	l.trim()
 }
 
-func (l *lru[K, V]) MustGetOrAdd(key K, addFunc AddFunc[V]) V {
-	v, err := l.GetOrAdd(key, addFunc)
-	if err != nil {
-		panic(err)
-	}
-
-	return v
-}
-
-func (l *lru[K, V]) Delete(key K) {
-	l.Lock()
-	defer l.Unlock()
-
-	v, ok := l.values[key]
+func (l *lru[K, V]) remove(key K) (V, bool) {
+	e, ok := l.values[key]
 	if !ok {
-		return
+		var empty V
+		return empty, false
 	}
 
-	l.entries.Remove(v.element)
+	l.cost -= e.Value.cost
+	l.entries.Remove(e)
 	delete(l.values, key)
+
+	return e.Value.value, true
 }
 
-func (l *lru[K, V]) add(key K, value V) {
-	if v, ok := l.values[key]; ok {
-		l.entries.MoveToFront(v.element)
-		v.value = value
-		l.values[key] = v
-		return
+func (l *lru[K, V]) resize(maxEntries int) {
+	l.maxCost = int64(maxEntries)
+	l.trim()
+}
+
+// trim evicts entries from the back until the total cost is no more than maxCost
+func (l *lru[K, V]) trim() {
+	for l.cost > l.maxCost {
+		back := l.entries.Back().Value.key
+		v, _ := l.remove(back)
+		l.evicted(back, v, ReasonCapacity)
 	}
+}
 
-	if l.entries.Len() == l.maxEntries {
-		back := l.entries.Back()
-		delete(l.values, back.Value.key)
-		l.entries.Remove(back)
+func (l *lru[K, V]) walk(fn func(K, V)) {
+	for e := l.entries.Back(); e != nil; e = e.Prev() {
+		fn(e.Value.key, e.Value.value)
 	}
+}
 
-	elem := l.entries.PushFront(keyValue[K, V]{
-		key:   key,
-		value: value,
-	})
+func (l *lru[K, V]) len() int {
+	return len(l.values)
+}
 
-	l.values[key] = listValue[K, V]{
-		element: elem,
-		value:   value,
-	}
+func (l *lru[K, V]) name() string {
+	return `lru`
 }
@@ -235,3 +235,55 @@ func TestLRU(t *testing.T) {
 		assert.Equal(t, 1, count)
 	})
 }
+
+func TestWeightedLRU(t *testing.T) {
+	weigher := func(_ int, v string) int64 {
+		return int64(len(v))
+	}
+
+	t.Run(`evict least recently used until cost fits`, func(t *testing.T) {
+		c := cache.WeightedLRU(10, weigher)
+
+		c.Add(1, `aaaa`)
+		c.Add(2, `bbbb`)
+		_, _ = c.Get(1)
+		c.Add(3, `cccc`)
+
+		_, ok := c.Get(1)
+		assert.True(t, ok)
+
+		_, ok = c.Get(2)
+		assert.False(t, ok)
+
+		_, ok = c.Get(3)
+		assert.True(t, ok)
+	})
+
+	t.Run(`reject entries larger than maxCost`, func(t *testing.T) {
+		c := cache.WeightedLRU(10, weigher)
+
+		c.Add(1, `aaaa`)
+		c.Add(2, `bbbbbbbbbbbb`)
+
+		_, ok := c.Get(2)
+		assert.False(t, ok)
+
+		_, ok = c.Get(1)
+		assert.True(t, ok)
+	})
+
+	t.Run(`replacing keeps the entry`, func(t *testing.T) {
+		c := cache.WeightedLRU(10, weigher)
+
+		c.Add(1, `aaaa`)
+		c.Add(2, `bbbb`)
+		c.Add(2, `bbbbbbbb`)
+
+		_, ok := c.Get(1)
+		assert.False(t, ok)
+
+		v, ok := c.Get(2)
+		assert.True(t, ok)
+		assert.Equal(t, `bbbbbbbb`, v)
+	})
+}
@@ -0,0 +1,155 @@
+/*
+metrics exports cache stats with expvar and in the Prometheus text format
+it is used to avoid depending on a Prometheus client library
+*/
+package metrics
+
+import (
+	"expvar"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/FallenTaters/cache"
+)
+
+// Source is implemented by every cache.Cache
+type Source interface {
+	Stats() cache.Stats
+	Policy() string
+}
+
+var (
+	mu      sync.RWMutex
+	sources = map[string]Source{}
+)
+
+// Register publishes the stats of c with expvar under name, and adds them to Handler.
+// Like expvar.Publish, it panics if name is already registered.
+func Register(name string, c Source) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if _, ok := sources[name]; ok {
+		panic(fmt.Sprintf(`metrics: cache %q is already registered`, name))
+	}
+
+	sources[name] = c
+	expvar.Publish(name, expvar.Func(func() any {
+		return toMap(c)
+	}))
+}
+
+// Handler serves the stats of all registered caches in the Prometheus text format,
+// labelled by cache name and policy
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(`Content-Type`, `text/plain; version=0.0.4; charset=utf-8`)
+		_ = Write(w)
+	})
+}
+
+type metric struct {
+	name  string
+	help  string
+	typ   string
+	value func(cache.Stats) float64
+}
+
+var metrics = []metric{
+	{`cache_hits_total`, `Number of lookups that found a value.`, `counter`, func(s cache.Stats) float64 { return float64(s.Hits) }},
+	{`cache_misses_total`, `Number of lookups that found no value.`, `counter`, func(s cache.Stats) float64 { return float64(s.Misses) }},
+	{`cache_loads_total`, `Number of addFunc calls that succeeded.`, `counter`, func(s cache.Stats) float64 { return float64(s.Loads) }},
+	{`cache_load_errors_total`, `Number of addFunc calls that failed.`, `counter`, func(s cache.Stats) float64 { return float64(s.LoadErrors) }},
+	{`cache_load_duration_seconds_total`, `Total time spent in addFunc calls.`, `counter`, func(s cache.Stats) float64 { return s.LoadTime.Seconds() }},
+	{`cache_entries`, `Current number of entries.`, `gauge`, func(s cache.Stats) float64 { return float64(s.Entries) }},
+}
+
+// Write writes the stats of all registered caches to w in the Prometheus text format
+func Write(w io.Writer) error {
+	names, stats, policies := snapshot()
+
+	var b strings.Builder
+	for _, m := range metrics {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.typ)
+		for i, name := range names {
+			fmt.Fprintf(&b, "%s{cache=%s,policy=%s} %v\n", m.name, quote(name), quote(policies[i]), m.value(stats[i]))
+		}
+	}
+
+	b.WriteString("# HELP cache_evictions_total Number of entries that left the cache.\n# TYPE cache_evictions_total counter\n")
+	for i, name := range names {
+		for _, reason := range reasons(stats[i]) {
+			fmt.Fprintf(&b, "cache_evictions_total{cache=%s,policy=%s,reason=%s} %d\n",
+				quote(name), quote(policies[i]), quote(reason.String()), stats[i].Evictions[reason])
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// snapshot returns the stats of all registered caches, sorted by name
+func snapshot() ([]string, []cache.Stats, []string) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	names := make([]string, 0, len(sources))
+	for name := range sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	stats := make([]cache.Stats, len(names))
+	policies := make([]string, len(names))
+	for i, name := range names {
+		stats[i] = sources[name].Stats()
+		policies[i] = sources[name].Policy()
+	}
+
+	return names, stats, policies
+}
+
+func toMap(c Source) map[string]any {
+	s := c.Stats()
+
+	evictions := make(map[string]uint64, len(s.Evictions))
+	for reason, n := range s.Evictions {
+		evictions[reason.String()] = n
+	}
+
+	return map[string]any{
+		`policy`:                c.Policy(),
+		`hits`:                  s.Hits,
+		`misses`:                s.Misses,
+		`loads`:                 s.Loads,
+		`load_errors`:           s.LoadErrors,
+		`load_duration_seconds`: s.LoadTime.Seconds(),
+		`evictions`:             evictions,
+		`entries`:               s.Entries,
+	}
+}
+
+func reasons(s cache.Stats) []cache.EvictionReason {
+	reasons := make([]cache.EvictionReason, 0, len(s.Evictions))
+	for reason := range s.Evictions {
+		reasons = append(reasons, reason)
+	}
+	sort.Slice(reasons, func(i, j int) bool {
+		return reasons[i] < reasons[j]
+	})
+
+	return reasons
+}
+
+// quote quotes a label value, escaping backslashes, double quotes and newlines
+func quote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+
+	return `"` + s + `"`
+}
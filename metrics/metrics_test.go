@@ -0,0 +1,74 @@
+package metrics_test
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/FallenTaters/cache"
+	"github.com/FallenTaters/cache/assert"
+	"github.com/FallenTaters/cache/metrics"
+)
+
+// runs makes cache names unique when tests run more than once
+var runs int
+
+func TestMetrics(t *testing.T) {
+	runs++
+	name := fmt.Sprintf(`items%d`, runs)
+
+	c := cache.TLRU[int, int](1, time.Hour)
+	metrics.Register(name, c)
+
+	c.Add(1, 1)
+	c.Add(2, 2)
+	_, _ = c.Get(3)
+	_, _ = c.GetOrAdd(3, func() (int, error) {
+		return 3, nil
+	})
+
+	t.Run(`prometheus`, func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		metrics.Handler().ServeHTTP(rec, httptest.NewRequest(`GET`, `/metrics`, nil))
+
+		body := rec.Body.String()
+		for _, line := range []string{
+			`# TYPE cache_hits_total counter`,
+			`cache_misses_total{cache="` + name + `",policy="tlru"} 2`,
+			`cache_loads_total{cache="` + name + `",policy="tlru"} 1`,
+			`cache_entries{cache="` + name + `",policy="tlru"} 1`,
+			`cache_evictions_total{cache="` + name + `",policy="tlru",reason="capacity"} 2`,
+			`cache_evictions_total{cache="` + name + `",policy="tlru",reason="expired"} 0`,
+		} {
+			assert.True(t, strings.Contains(body, line+"\n"), line)
+		}
+		assert.True(t, strings.HasPrefix(rec.Header().Get(`Content-Type`), `text/plain`))
+	})
+
+	t.Run(`expvar`, func(t *testing.T) {
+		v := expvar.Get(name)
+		assert.NotNil(t, v)
+
+		var m struct {
+			Policy    string            `json:"policy"`
+			Misses    uint64            `json:"misses"`
+			Evictions map[string]uint64 `json:"evictions"`
+		}
+		assert.NoError(t, json.Unmarshal([]byte(v.String()), &m))
+		assert.Equal(t, `tlru`, m.Policy)
+		assert.Equal(t, uint64(2), m.Misses)
+		assert.Equal(t, uint64(2), m.Evictions[`capacity`])
+	})
+
+	t.Run(`register twice`, func(t *testing.T) {
+		defer func() {
+			assert.NotNil(t, recover())
+		}()
+
+		metrics.Register(name, c)
+	})
+}
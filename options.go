@@ -0,0 +1,127 @@
+package cache
+
+import "time"
+
+// Option configures optional behaviour of a cache
+// Options that do not apply to a constructor are ignored
+type Option[K comparable, V any] func(*options[K, V])
+
+type options[K comparable, V any] struct {
+	janitorInterval time.Duration
+	hasher          func(K) uint64
+	onEvict         func(K, V, EvictionReason)
+	codec           Codec
+	compactInterval time.Duration
+
+	staleWhileRevalidate time.Duration
+	staleIfError         time.Duration
+	onStaleError         func(K, error)
+	refreshAhead         float64
+	earlyExpiration      float64
+	errorTTL             time.Duration
+	cacheableError       func(error) bool
+
+	// expired reports entries evicted for capacity as expired if it returns true
+	expired func(V) bool
+
+	// hidden reports entries that are not counted as evictions or passed to onEvict if it returns true
+	hidden func(V) bool
+}
+
+func newOptions[K comparable, V any](opts []Option[K, V]) options[K, V] {
+	o := options[K, V]{
+		codec: GobCodec,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// WithJanitor makes TLRU and TFIFO remove expired entries in the background every interval,
+// so expired entries do not push out valid ones. The janitor runs until Close is called.
+func WithJanitor[K comparable, V any](interval time.Duration) Option[K, V] {
+	return func(o *options[K, V]) {
+		o.janitorInterval = interval
+	}
+}
+
+// WithHasher makes Sharded use hasher to pick the shard for a key
+func WithHasher[K comparable, V any](hasher func(K) uint64) Option[K, V] {
+	return func(o *options[K, V]) {
+		o.hasher = hasher
+	}
+}
+
+// OnEvict calls onEvict for every entry that leaves the cache, with the reason why.
+// It is called after the cache is unlocked, so onEvict may use the cache.
+func OnEvict[K comparable, V any](onEvict func(K, V, EvictionReason)) Option[K, V] {
+	return func(o *options[K, V]) {
+		o.onEvict = onEvict
+	}
+}
+
+// WithStaleWhileRevalidate makes GetOrAdd of TLRU and TFIFO return values that expired less than grace ago,
+// while a single addFunc runs in the background to replace the value
+func WithStaleWhileRevalidate[K comparable, V any](grace time.Duration) Option[K, V] {
+	return func(o *options[K, V]) {
+		o.staleWhileRevalidate = grace
+	}
+}
+
+// WithStaleIfError makes GetOrAdd of TLRU, TFIFO and TLFU return the expired value instead of an error
+// if addFunc fails, as long as the value expired less than maxStale ago.
+// The error is passed to onError instead, if it is not nil.
+func WithStaleIfError[K comparable, V any](maxStale time.Duration, onError func(K, error)) Option[K, V] {
+	return func(o *options[K, V]) {
+		o.staleIfError = maxStale
+		o.onStaleError = onError
+	}
+}
+
+// WithRefreshAhead makes TLRU, TFIFO and TLFU reload a value in the background when it is accessed
+// in the last fraction of its maxAge, using the addFunc it was loaded with. Values loaded by GetOrAddMany are reloaded
+// by calling its BatchAddFunc for their key alone. Values added with Add are not reloaded.
+// For example, 0.2 reloads values that are accessed in the last 20% of their maxAge.
+func WithRefreshAhead[K comparable, V any](fraction float64) Option[K, V] {
+	return func(o *options[K, V]) {
+		o.refreshAhead = fraction
+	}
+}
+
+// WithEarlyExpiration makes each hit on TLRU, TFIFO and TLFU reload the value in the background with a probability
+// that rises as it gets closer to expiring, weighted by how long its addFunc took. This spreads out reloads of the same key
+// across processes. A beta of 1 is a good default, larger values reload earlier. Values loaded by GetOrAddMany are
+// reloaded like with WithRefreshAhead, values added with Add are not reloaded.
+func WithEarlyExpiration[K comparable, V any](beta float64) Option[K, V] {
+	return func(o *options[K, V]) {
+		o.earlyExpiration = beta
+	}
+}
+
+// WithErrorCaching makes TLRU, TFIFO and TLFU cache errors of addFunc for ttl,
+// so GetOrAdd returns the same error until it expires instead of calling addFunc again.
+// Only errors for which cacheable returns true are cached. If cacheable is nil, all errors are cached.
+// Errors of the BatchAddFunc of GetOrAddMany are not cached, because they are not tied to a single key.
+func WithErrorCaching[K comparable, V any](ttl time.Duration, cacheable func(error) bool) Option[K, V] {
+	return func(o *options[K, V]) {
+		o.errorTTL = ttl
+		o.cacheableError = cacheable
+	}
+}
+
+// WithCodec sets the Codec used by Save and Load, the default is GobCodec
+func WithCodec[K comparable, V any](codec Codec) Option[K, V] {
+	return func(o *options[K, V]) {
+		o.codec = codec
+	}
+}
+
+// WithCompaction makes OpenWAL compact the log in the background every interval,
+// writing all entries to a snapshot and emptying the log. Compaction runs until Close is called.
+func WithCompaction[K comparable, V any](interval time.Duration) Option[K, V] {
+	return func(o *options[K, V]) {
+		o.compactInterval = interval
+	}
+}
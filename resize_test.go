@@ -0,0 +1,131 @@
+package cache_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/FallenTaters/cache"
+	"github.com/FallenTaters/cache/assert"
+)
+
+func TestClear(t *testing.T) {
+	evictions, onEvict := recordEvictions()
+	c := cache.TLRU(10, time.Hour, onEvict)
+	c.Add(1, 1)
+	c.Add(2, 2)
+
+	c.Clear()
+
+	assert.Equal(t, 0, c.Len())
+	_, ok := c.Get(1)
+	assert.False(t, ok)
+
+	assert.Equal(t, 2, len(*evictions))
+	for _, e := range *evictions {
+		assert.Equal(t, cache.ReasonCleared, e.reason)
+	}
+	assert.Equal(t, uint64(2), c.Stats().Evictions[cache.ReasonCleared])
+}
+
+func TestResize(t *testing.T) {
+	for name, newCache := range map[string]func(opt cache.Option[int, int]) cache.Cache[int, int]{
+		`fifo`:    func(opt cache.Option[int, int]) cache.Cache[int, int] { return cache.FIFO(10, opt) },
+		`lru`:     func(opt cache.Option[int, int]) cache.Cache[int, int] { return cache.LRU(10, opt) },
+		`lfu`:     func(opt cache.Option[int, int]) cache.Cache[int, int] { return cache.LFU(10, opt) },
+		`tinylfu`: func(opt cache.Option[int, int]) cache.Cache[int, int] { return cache.TinyLFU(10, opt) },
+		`arc`:     func(opt cache.Option[int, int]) cache.Cache[int, int] { return cache.ARC(10, opt) },
+		`sieve`:   func(opt cache.Option[int, int]) cache.Cache[int, int] { return cache.SIEVE(10, opt) },
+		`s3fifo`:  func(opt cache.Option[int, int]) cache.Cache[int, int] { return cache.S3FIFO(10, opt) },
+		`tfifo`:   func(opt cache.Option[int, int]) cache.Cache[int, int] { return cache.TFIFO(10, time.Hour, opt) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			evictions, onEvict := recordEvictions()
+			c := newCache(onEvict)
+			for key := range 10 {
+				c.Add(key, key)
+			}
+			assert.Equal(t, 10, c.Len())
+
+			c.Resize(5)
+			assert.Equal(t, 5, c.Len())
+			assert.Equal(t, 5, len(*evictions))
+			for _, e := range *evictions {
+				assert.Equal(t, cache.ReasonCapacity, e.reason)
+			}
+
+			c.Resize(8)
+			for key := 10; key < 13; key++ {
+				c.Add(key, key)
+			}
+			assert.Equal(t, 8, c.Len())
+
+			c.Resize(-1)
+			assert.Equal(t, 0, c.Len())
+			for key := range 10 {
+				c.Add(key, key)
+			}
+			assert.Equal(t, 0, c.Len())
+		})
+	}
+
+	t.Run(`weighted`, func(t *testing.T) {
+		c := cache.WeightedLRU[int, int](10, func(_, v int) int64 { return int64(v) })
+		c.Add(1, 4)
+		c.Add(2, 4)
+
+		c.Resize(5)
+
+		assert.Equal(t, 1, c.Len())
+		_, ok := c.Get(2)
+		assert.True(t, ok)
+
+		c.Resize(-1)
+		assert.Equal(t, 0, c.Len())
+	})
+
+	t.Run(`sharded total is exact`, func(t *testing.T) {
+		c := cache.Sharded(4, func() cache.Cache[int, int] { return cache.ARC[int, int](10) },
+			cache.WithHasher[int, int](func(key int) uint64 { return uint64(key) }))
+
+		for _, size := range []int{7, 2, 0} {
+			c.Resize(size)
+			for key := range 40 {
+				c.Add(key, key)
+			}
+			assert.Equal(t, size, c.Len())
+		}
+	})
+
+	t.Run(`sharded`, func(t *testing.T) {
+		c := cache.Sharded(2, func() cache.Cache[int, int] { return cache.LRU[int, int](10) })
+		for key := range 20 {
+			c.Add(key, key)
+		}
+
+		c.Resize(4)
+		assert.True(t, c.Len() <= 4)
+
+		c.Resize(1)
+		for key := range 20 {
+			c.Add(key, key)
+		}
+		assert.True(t, c.Len() <= 1)
+
+		c.Resize(-1)
+		assert.Equal(t, 0, c.Len())
+	})
+}
+
+func TestPurgeExpired(t *testing.T) {
+	evictions, onEvict := recordEvictions()
+	c := cache.TLRU(10, cacheDuration, onEvict)
+	c.Add(1, 1)
+	c.AddWithTTL(2, 2, time.Hour)
+	sleep()
+
+	c.PurgeExpired()
+
+	assert.Equal(t, 1, c.Stats().Entries)
+	assert.Equal(t, 1, len(*evictions))
+	assert.Equal(t, cache.ReasonExpired, (*evictions)[0].reason)
+}
@@ -0,0 +1,242 @@
+package cache
+
+import (
+	"sync/atomic"
+
+	"github.com/FallenTaters/cache/list"
+)
+
+const s3FIFOMaxFreq = 3
+
+type s3FIFOEntry[K comparable, V any] struct {
+	keyValue[K, V]
+
+	freq atomic.Int32
+	main bool
+}
+
+// s3FIFO uses three FIFO queues: new entries go into small. Entries that were used while in small move to main,
+// the others are evicted and their keys remembered in ghost. Keys found in ghost are added to main directly.
+type s3FIFO[K comparable, V any] struct {
+	evictor[K, V]
+
+	maxEntries int
+	maxSmall   int
+	maxGhost   int
+
+	small  *list.List[*s3FIFOEntry[K, V]]
+	main   *list.List[*s3FIFOEntry[K, V]]
+	values map[K]*list.Element[*s3FIFOEntry[K, V]]
+
+	ghost     *list.List[K]
+	ghostKeys map[K]*list.Element[K]
+}
+
+// S3FIFO uses 10% of maxEntries for the small queue. Get does not move entries, so it only takes a read lock.
+func S3FIFO[K comparable, V any](maxEntries int, opts ...Option[K, V]) Cache[K, V] {
+	s := &s3FIFO[K, V]{
+		small:  list.New[*s3FIFOEntry[K, V]](),
+		main:   list.New[*s3FIFOEntry[K, V]](),
+		values: make(map[K]*list.Element[*s3FIFOEntry[K, V]], maxEntries),
+		ghost:  list.New[K](),
+	}
+	s.setSize(maxEntries)
+	s.ghostKeys = make(map[K]*list.Element[K], s.maxGhost)
+
+	return newBase[K, V](s, opts)
+}
+
+// setSize sets maxEntries and the sizes of the small and ghost queues derived from it
+func (s *s3FIFO[K, V]) setSize(maxEntries int) {
+	s.maxEntries = maxEntries
+
+	s.maxSmall = maxEntries / 10
+	if s.maxSmall < 1 {
+		s.maxSmall = 1
+	}
+
+	s.maxGhost = maxEntries - s.maxSmall
+	if s.maxGhost < 1 {
+		s.maxGhost = 1
+	}
+}
+
+func (s *s3FIFO[K, V]) get(key K) (V, bool) {
+	e, ok := s.values[key]
+	if !ok {
+		var empty V
+		return empty, false
+	}
+
+	e.Value.hit()
+
+	return e.Value.value, true
+}
+
+func (s *s3FIFO[K, V]) peek(key K) (V, bool) {
+	e, ok := s.values[key]
+	if !ok {
+		var empty V
+		return empty, false
+	}
+
+	return e.Value.value, true
+}
+
+func (s *s3FIFO[K, V]) readOnlyGet() bool {
+	return true
+}
+
+func (s *s3FIFO[K, V]) add(key K, value V) {
+	if e, ok := s.values[key]; ok {
+		s.evicted(key, e.Value.value, ReasonReplaced)
+		e.Value.value = value
+		e.Value.hit()
+		return
+	}
+
+	if s.maxEntries <= 0 {
+		s.evicted(key, value, ReasonCapacity)
+		return
+	}
+
+	for len(s.values) > 0 && len(s.values) >= s.maxEntries {
+		s.evict()
+	}
+
+	entry := &s3FIFOEntry[K, V]{
+		keyValue: keyValue[K, V]{key: key, value: value},
+	}
+
+	if g, ok := s.ghostKeys[key]; ok {
+		s.ghost.Remove(g)
+		delete(s.ghostKeys, key)
+
+		entry.main = true
+		s.values[key] = s.main.PushFront(entry)
+		return
+	}
+
+	s.values[key] = s.small.PushFront(entry)
+}
+
+func (s *s3FIFO[K, V]) remove(key K) (V, bool) {
+	e, ok := s.values[key]
+	if !ok {
+		var empty V
+		return empty, false
+	}
+
+	s.queue(e.Value).Remove(e)
+	delete(s.values, key)
+
+	return e.Value.value, true
+}
+
+func (s *s3FIFO[K, V]) resize(maxEntries int) {
+	s.setSize(maxEntries)
+
+	for len(s.values) > s.maxEntries {
+		s.evict()
+	}
+
+	for s.ghost.Len() > s.maxGhost {
+		oldest := s.ghost.Back()
+		delete(s.ghostKeys, oldest.Value)
+		s.ghost.Remove(oldest)
+	}
+}
+
+func (s *s3FIFO[K, V]) walk(fn func(K, V)) {
+	for _, l := range []*list.List[*s3FIFOEntry[K, V]]{s.small, s.main} {
+		for e := l.Back(); e != nil; e = e.Prev() {
+			fn(e.Value.key, e.Value.value)
+		}
+	}
+}
+
+func (s *s3FIFO[K, V]) len() int {
+	return len(s.values)
+}
+
+func (s *s3FIFO[K, V]) name() string {
+	return `s3fifo`
+}
+
+func (s *s3FIFO[K, V]) evict() {
+	if s.small.Len() >= s.maxSmall || s.main.Len() == 0 {
+		s.evictSmall()
+	} else {
+		s.evictMain()
+	}
+}
+
+// evictSmall moves the back of small to main if it was used, otherwise it evicts it to ghost
+func (s *s3FIFO[K, V]) evictSmall() {
+	back := s.small.Back()
+	if back == nil {
+		return
+	}
+
+	entry := s.small.Remove(back)
+	if entry.freq.Load() > 0 {
+		if s.main.Len() >= s.maxEntries-s.maxSmall {
+			s.evictMain()
+		}
+
+		entry.freq.Store(0)
+		entry.main = true
+		s.values[entry.key] = s.main.PushFront(entry)
+		return
+	}
+
+	delete(s.values, entry.key)
+	s.evicted(entry.key, entry.value, ReasonCapacity)
+
+	if s.ghost.Len() >= s.maxGhost {
+		oldest := s.ghost.Back()
+		delete(s.ghostKeys, oldest.Value)
+		s.ghost.Remove(oldest)
+	}
+	s.ghostKeys[entry.key] = s.ghost.PushFront(entry.key)
+}
+
+// evictMain evicts the first entry from the back of main that was not used,
+// used entries are reinserted at the front with their frequency decremented
+func (s *s3FIFO[K, V]) evictMain() {
+	for {
+		back := s.main.Back()
+		if back == nil {
+			return
+		}
+
+		if freq := back.Value.freq.Load(); freq > 0 {
+			back.Value.freq.Store(freq - 1)
+			s.main.MoveToFront(back)
+			continue
+		}
+
+		s.main.Remove(back)
+		delete(s.values, back.Value.key)
+		s.evicted(back.Value.key, back.Value.value, ReasonCapacity)
+		return
+	}
+}
+
+func (s *s3FIFO[K, V]) queue(entry *s3FIFOEntry[K, V]) *list.List[*s3FIFOEntry[K, V]] {
+	if entry.main {
+		return s.main
+	}
+
+	return s.small
+}
+
+// hit increments the frequency up to s3FIFOMaxFreq, it is safe for concurrent use
+func (e *s3FIFOEntry[K, V]) hit() {
+	for {
+		freq := e.freq.Load()
+		if freq >= s3FIFOMaxFreq || e.freq.CompareAndSwap(freq, freq+1) {
+			return
+		}
+	}
+}
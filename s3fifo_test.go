@@ -0,0 +1,124 @@
+package cache_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/FallenTaters/cache"
+	"github.com/FallenTaters/cache/assert"
+)
+
+func newS3FIFO() cache.Cache[int, int] {
+	return cache.S3FIFO[int, int](10)
+}
+
+func TestS3FIFO(t *testing.T) {
+	t.Run(`get non-existing`, func(t *testing.T) {
+		c := newS3FIFO()
+
+		v, ok := c.Get(1)
+		assert.False(t, ok)
+		assert.Equal(t, 0, v)
+	})
+
+	t.Run(`add and get`, func(t *testing.T) {
+		c := newS3FIFO()
+
+		c.Add(1, 1)
+
+		v, ok := c.Get(1)
+		assert.True(t, ok)
+		assert.Equal(t, 1, v)
+	})
+
+	t.Run(`limit number of entries`, func(t *testing.T) {
+		c := cache.S3FIFO[int, int](2)
+
+		c.Add(1, 1)
+		c.Add(2, 2)
+		c.Add(3, 3)
+
+		_, ok := c.Get(1)
+		assert.False(t, ok)
+
+		_, ok = c.Get(2)
+		assert.True(t, ok)
+
+		_, ok = c.Get(3)
+		assert.True(t, ok)
+	})
+
+	t.Run(`used entries survive a scan`, func(t *testing.T) {
+		c := newS3FIFO()
+
+		for key := 0; key < 5; key++ {
+			_ = c.MustGetOrAdd(key, newAddFunc(key, nil))
+			_, _ = c.Get(key)
+		}
+
+		for key := 100; key < 200; key++ {
+			_ = c.MustGetOrAdd(key, newAddFunc(key, nil))
+		}
+
+		for key := 0; key < 5; key++ {
+			v, ok := c.Get(key)
+			assert.True(t, ok)
+			assert.Equal(t, key, v)
+		}
+	})
+
+	t.Run(`return error`, func(t *testing.T) {
+		myErr := errors.New(`myErr`)
+		c := newS3FIFO()
+
+		_, err := c.GetOrAdd(1, newAddFunc(0, myErr))
+		assert.ErrorIs(t, myErr, err)
+	})
+
+	t.Run(`delete`, func(t *testing.T) {
+		c := newS3FIFO()
+
+		_ = c.MustGetOrAdd(1, newAddFunc(1, nil))
+		c.Delete(1)
+
+		v, ok := c.Get(1)
+		assert.False(t, ok)
+		assert.Equal(t, 0, v)
+	})
+
+	t.Run(`concurrent operations`, func(t *testing.T) {
+		c := newS3FIFO()
+
+		var wg sync.WaitGroup
+
+		wg.Add(concurrentCount)
+		go func() {
+			for i := 0; i < concurrentCount; i++ {
+				expected := i
+				go func() {
+					actual, err := c.GetOrAdd(expected, newAddFunc(expected, nil))
+					assert.Equal(t, expected, actual)
+					assert.NoError(t, err)
+
+					_, _ = c.Get(expected)
+
+					wg.Done()
+				}()
+			}
+		}()
+
+		wg.Add(concurrentCount)
+		go func() {
+			for i := concurrentCount - 1; i >= 0; i-- {
+				expected := i
+				go func() {
+					c.Delete(expected)
+					wg.Done()
+				}()
+			}
+		}()
+
+		wg.Wait()
+	})
+}
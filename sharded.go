@@ -0,0 +1,412 @@
+package cache
+
+import (
+	"cmp"
+	"context"
+	"hash/maphash"
+	"io"
+	"iter"
+	"sync"
+	"time"
+)
+
+type sharded[K comparable, V any] struct {
+	shards []Cache[K, V]
+	hasher func(K) uint64
+	codec  Codec
+}
+
+// Sharded spreads keys over a number of independent caches created by newShard, so they do not share a lock.
+// Keys are hashed with hash/maphash, unless a hasher is passed with WithHasher.
+// Use ShardedT for time-aware shards, so they can be closed.
+func Sharded[K comparable, V any](shards int, newShard func() Cache[K, V], opts ...Option[K, V]) Cache[K, V] {
+	if shards < 1 {
+		shards = 1
+	}
+
+	o := newOptions(opts)
+
+	s := &sharded[K, V]{
+		shards: make([]Cache[K, V], shards),
+		hasher: o.hasher,
+		codec:  o.codec,
+	}
+	for i := range s.shards {
+		s.shards[i] = newShard()
+	}
+
+	if s.hasher == nil {
+		seed := maphash.MakeSeed()
+		s.hasher = func(key K) uint64 {
+			return maphash.Comparable(seed, key)
+		}
+	}
+
+	return s
+}
+
+// ShardedT is like Sharded for time-aware caches such as TLRU. PurgeExpired and Close are passed on to every shard.
+func ShardedT[K comparable, V any](shards int, newShard func() TCache[K, V], opts ...Option[K, V]) TCache[K, V] {
+	s := Sharded(shards, func() Cache[K, V] {
+		return newShard()
+	}, opts...)
+
+	return shardedT[K, V]{s.(*sharded[K, V])}
+}
+
+type shardedT[K comparable, V any] struct {
+	*sharded[K, V]
+}
+
+func (s shardedT[K, V]) AddWithTTL(key K, value V, ttl time.Duration) {
+	s.tshard(key).AddWithTTL(key, value, ttl)
+}
+
+func (s shardedT[K, V]) GetOrAddWithTTL(key K, addFunc AddFunc[V], ttl time.Duration) (V, error) {
+	return s.tshard(key).GetOrAddWithTTL(key, addFunc, ttl)
+}
+
+func (s shardedT[K, V]) GetOrAddTTLFunc(key K, addFunc TTLAddFunc[V]) (V, error) {
+	return s.tshard(key).GetOrAddTTLFunc(key, addFunc)
+}
+
+func (s shardedT[K, V]) PurgeExpired() {
+	for _, shard := range s.shards {
+		shard.(TCache[K, V]).PurgeExpired()
+	}
+}
+
+func (s shardedT[K, V]) Close() {
+	for _, shard := range s.shards {
+		shard.(TCache[K, V]).Close()
+	}
+}
+
+func (s shardedT[K, V]) tshard(key K) TCache[K, V] {
+	return s.shard(key).(TCache[K, V])
+}
+
+func (s *sharded[K, V]) Get(key K) (V, bool) {
+	return s.shard(key).Get(key)
+}
+
+func (s *sharded[K, V]) Add(key K, value V) {
+	s.shard(key).Add(key, value)
+}
+
+func (s *sharded[K, V]) GetOrAdd(key K, addFunc AddFunc[V]) (V, error) {
+	return s.shard(key).GetOrAdd(key, addFunc)
+}
+
+func (s *sharded[K, V]) GetOrAddCtx(ctx context.Context, key K, addFunc AddFuncCtx[V]) (V, error) {
+	return s.shard(key).GetOrAddCtx(ctx, key, addFunc)
+}
+
+func (s *sharded[K, V]) MustGetOrAdd(key K, addFunc AddFunc[V]) V {
+	return s.shard(key).MustGetOrAdd(key, addFunc)
+}
+
+// GetOrAddMany runs GetOrAddMany on all shards with missing keys at the same time.
+// The shards share a single call of addFunc for the keys they load, shards that are not
+// caches of this package call addFunc on their own
+func (s *sharded[K, V]) GetOrAddMany(keys []K, addFunc BatchAddFunc[K, V]) (map[K]V, error) {
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		values = make(map[K]V, len(keys))
+		err    error
+	)
+
+	groups := s.group(keys)
+	batch := newSharedBatch(len(groups), addFunc)
+
+	for i, keys := range groups {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			var shardValues map[K]V
+			var shardErr error
+			if shard, ok := s.shards[i].(batchLoader[K, V]); ok {
+				shardValues, shardErr = shard.getOrAddMany(keys, batch.load, batch.claim)
+			} else {
+				batch.claim(nil)
+				shardValues, shardErr = s.shards[i].GetOrAddMany(keys, addFunc)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			for key, value := range shardValues {
+				values[key] = value
+			}
+			err = cmp.Or(err, shardErr)
+		}()
+	}
+	wg.Wait()
+
+	return values, err
+}
+
+// batchLoader is implemented by the caches of this package, so Sharded can load the keys of all shards in one batch
+type batchLoader[K comparable, V any] interface {
+	getOrAddMany(keys []K, addFunc BatchAddFunc[K, V], claimed func([]K)) (map[K]V, error)
+}
+
+// sharedBatch calls addFunc once for the keys that all shards claimed, and gives every shard its part of the result
+type sharedBatch[K comparable, V any] struct {
+	addFunc BatchAddFunc[K, V]
+
+	// mu guards shards and keys, claimed is closed when the last shard has claimed its keys
+	mu      sync.Mutex
+	shards  int
+	keys    []K
+	claimed chan struct{}
+
+	once   sync.Once
+	values map[K]V
+	err    error
+}
+
+func newSharedBatch[K comparable, V any](shards int, addFunc BatchAddFunc[K, V]) *sharedBatch[K, V] {
+	b := &sharedBatch[K, V]{
+		addFunc: addFunc,
+		shards:  shards,
+		claimed: make(chan struct{}),
+	}
+	if shards == 0 {
+		close(b.claimed)
+	}
+
+	return b
+}
+
+// claim adds the keys that a shard will load, every shard claims once, before it waits for anything
+func (b *sharedBatch[K, V]) claim(keys []K) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.keys = append(b.keys, keys...)
+	b.shards--
+	if b.shards == 0 {
+		close(b.claimed)
+	}
+}
+
+// load is the BatchAddFunc of a shard, it waits until all shards have claimed their keys
+func (b *sharedBatch[K, V]) load(missing []K) (map[K]V, error) {
+	<-b.claimed
+
+	b.once.Do(func() {
+		defer func() {
+			if r := recover(); r != nil {
+				b.err = loaderPanic(r)
+			}
+		}()
+
+		b.values, b.err = b.addFunc(b.keys)
+	})
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	values := make(map[K]V, len(missing))
+	for _, key := range missing {
+		if value, ok := b.values[key]; ok {
+			values[key] = value
+		}
+	}
+
+	return values, nil
+}
+
+func (s *sharded[K, V]) Delete(key K) {
+	s.shard(key).Delete(key)
+}
+
+func (s *sharded[K, V]) GetMany(keys []K) map[K]V {
+	values := make(map[K]V, len(keys))
+	for i, keys := range s.group(keys) {
+		for key, value := range s.shards[i].GetMany(keys) {
+			values[key] = value
+		}
+	}
+
+	return values
+}
+
+func (s *sharded[K, V]) AddMany(values map[K]V) {
+	groups := make(map[int]map[K]V)
+	for key, value := range values {
+		i := s.index(key)
+		if groups[i] == nil {
+			groups[i] = make(map[K]V)
+		}
+		groups[i][key] = value
+	}
+
+	for i, values := range groups {
+		s.shards[i].AddMany(values)
+	}
+}
+
+func (s *sharded[K, V]) DeleteMany(keys []K) {
+	for i, keys := range s.group(keys) {
+		s.shards[i].DeleteMany(keys)
+	}
+}
+
+func (s *sharded[K, V]) Compute(key K, fn ComputeFunc[V]) (V, bool) {
+	return s.shard(key).Compute(key, fn)
+}
+
+func (s *sharded[K, V]) AddIfAbsent(key K, value V) bool {
+	return s.shard(key).AddIfAbsent(key, value)
+}
+
+// Len returns the sum of the lengths of all shards
+func (s *sharded[K, V]) Len() int {
+	var total int
+	for _, shard := range s.shards {
+		total += shard.Len()
+	}
+
+	return total
+}
+
+func (s *sharded[K, V]) Peek(key K) (V, bool) {
+	return s.shard(key).Peek(key)
+}
+
+// Keys returns the keys of all shards, each shard in eviction order
+func (s *sharded[K, V]) Keys() []K {
+	var keys []K
+	for _, shard := range s.shards {
+		keys = append(keys, shard.Keys()...)
+	}
+
+	return keys
+}
+
+// All iterates over all shards, each shard in eviction order. The snapshot of a shard is taken when the iteration reaches it
+func (s *sharded[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for _, shard := range s.shards {
+			for key, value := range shard.All() {
+				if !yield(key, value) {
+					return
+				}
+			}
+		}
+	}
+}
+
+func (s *sharded[K, V]) Clear() {
+	for _, shard := range s.shards {
+		shard.Clear()
+	}
+}
+
+// Resize divides maxEntries over the shards, the first maxEntries % shards shards get one more
+func (s *sharded[K, V]) Resize(maxEntries int) {
+	maxEntries = max(maxEntries, 0)
+	perShard, rest := maxEntries/len(s.shards), maxEntries%len(s.shards)
+	for i, shard := range s.shards {
+		if i < rest {
+			shard.Resize(perShard + 1)
+		} else {
+			shard.Resize(perShard)
+		}
+	}
+}
+
+// Save writes the entries of all shards as one stream, each shard in eviction order
+func (s *sharded[K, V]) Save(w io.Writer) error {
+	return encodeEntries(s.codec, w, s.entries())
+}
+
+func (s *sharded[K, V]) Load(r io.Reader) error {
+	entries, err := decodeEntries[K, V](s.codec, r)
+	if err != nil {
+		return err
+	}
+
+	s.restore(entries)
+	return nil
+}
+
+func (s *sharded[K, V]) entries() []entry[K, V] {
+	var entries []entry[K, V]
+	for _, shard := range s.shards {
+		entries = append(entries, entriesOf(shard)...)
+	}
+
+	return entries
+}
+
+func (s *sharded[K, V]) peekEntry(key K) (entry[K, V], bool) {
+	return peekEntryOf(s.shard(key), key)
+}
+
+func (s *sharded[K, V]) restore(entries []entry[K, V]) {
+	groups := make(map[int][]entry[K, V])
+	for _, e := range entries {
+		i := s.index(e.Key)
+		groups[i] = append(groups[i], e)
+	}
+
+	for i, entries := range groups {
+		restoreTo(s.shards[i], entries)
+	}
+}
+
+// Stats returns the sum of the stats of all shards
+func (s *sharded[K, V]) Stats() Stats {
+	var total Stats
+	for _, shard := range s.shards {
+		total = total.add(shard.Stats())
+	}
+
+	return total
+}
+
+func (s *sharded[K, V]) ResetStats() {
+	for _, shard := range s.shards {
+		shard.ResetStats()
+	}
+}
+
+// Policy returns the policy of the shards
+func (s *sharded[K, V]) Policy() string {
+	return s.shards[0].Policy()
+}
+
+// watch watches all shards, it returns false if a shard cannot be watched
+func (s *sharded[K, V]) watch(onChange func(K)) bool {
+	watched := true
+	for _, shard := range s.shards {
+		w, ok := shard.(watcher[K])
+		watched = ok && w.watch(onChange) && watched
+	}
+
+	return watched
+}
+
+func (s *sharded[K, V]) shard(key K) Cache[K, V] {
+	return s.shards[s.index(key)]
+}
+
+func (s *sharded[K, V]) index(key K) int {
+	return int(s.hasher(key) % uint64(len(s.shards)))
+}
+
+// group groups keys by the index of their shard, shards cannot be map keys because they may not be comparable
+func (s *sharded[K, V]) group(keys []K) map[int][]K {
+	groups := make(map[int][]K)
+	for _, key := range keys {
+		i := s.index(key)
+		groups[i] = append(groups[i], key)
+	}
+
+	return groups
+}
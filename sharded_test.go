@@ -0,0 +1,194 @@
+package cache_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/FallenTaters/cache"
+	"github.com/FallenTaters/cache/assert"
+)
+
+func newSharded() cache.Cache[int, int] {
+	return cache.Sharded(4, newLRU)
+}
+
+func TestSharded(t *testing.T) {
+	t.Run(`get non-existing`, func(t *testing.T) {
+		c := newSharded()
+
+		v, ok := c.Get(1)
+		assert.False(t, ok)
+		assert.Equal(t, 0, v)
+	})
+
+	t.Run(`add and get`, func(t *testing.T) {
+		c := newSharded()
+
+		for key := 0; key < 8; key++ {
+			c.Add(key, key)
+		}
+
+		var found int
+		for key := 0; key < 8; key++ {
+			if v, ok := c.Get(key); ok {
+				assert.Equal(t, key, v)
+				found++
+			}
+		}
+		assert.True(t, found > 2, `keys should be spread over shards`)
+	})
+
+	t.Run(`custom hasher`, func(t *testing.T) {
+		c := cache.Sharded(4, newLRU, cache.WithHasher[int, int](func(key int) uint64 {
+			return 0
+		}))
+
+		c.Add(1, 1)
+		c.Add(2, 2)
+		c.Add(3, 3)
+
+		_, ok := c.Get(1)
+		assert.False(t, ok, `all keys should be in the same shard`)
+	})
+
+	t.Run(`return error`, func(t *testing.T) {
+		myErr := errors.New(`myErr`)
+		c := newSharded()
+
+		_, err := c.GetOrAdd(1, newAddFunc(0, myErr))
+		assert.ErrorIs(t, myErr, err)
+	})
+
+	t.Run(`panic for must`, func(t *testing.T) {
+		myErr := errors.New(`myErr`)
+		defer func() {
+			v := recover()
+			assert.ErrorIs(t, myErr, v.(error))
+		}()
+
+		c := newSharded()
+
+		c.MustGetOrAdd(1, newAddFunc(0, myErr))
+	})
+
+	t.Run(`delete`, func(t *testing.T) {
+		c := newSharded()
+
+		_ = c.MustGetOrAdd(1, newAddFunc(1, nil))
+		c.Delete(1)
+
+		v, ok := c.Get(1)
+		assert.False(t, ok)
+		assert.Equal(t, 0, v)
+	})
+
+	t.Run(`concurrent operations`, func(t *testing.T) {
+		c := newSharded()
+
+		var wg sync.WaitGroup
+
+		wg.Add(concurrentCount)
+		go func() {
+			for i := 0; i < concurrentCount; i++ {
+				expected := i
+				go func() {
+					actual, err := c.GetOrAdd(expected, newAddFunc(expected, nil))
+					assert.Equal(t, expected, actual)
+					assert.NoError(t, err)
+
+					wg.Done()
+				}()
+			}
+		}()
+
+		wg.Add(concurrentCount)
+		go func() {
+			for i := concurrentCount - 1; i >= 0; i-- {
+				expected := i
+				go func() {
+					c.Delete(expected)
+					wg.Done()
+				}()
+			}
+		}()
+
+		wg.Wait()
+	})
+
+	t.Run(`cache stampede prevention`, func(t *testing.T) {
+		c := newSharded()
+
+		var count int
+		addFunc := func() (int, error) {
+			time.Sleep(10 * time.Millisecond)
+			count++
+			return 1, nil
+		}
+
+		var wg sync.WaitGroup
+		wg.Add(10)
+		for i := 0; i < 10; i++ {
+			go func() {
+				actual, err := c.GetOrAdd(1, addFunc)
+				assert.Equal(t, 1, actual)
+				assert.NoError(t, err)
+
+				wg.Done()
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, 1, count)
+	})
+}
+
+func newShardedT(opts ...cache.Option[int, int]) cache.TCache[int, int] {
+	return cache.ShardedT(4, func() cache.TCache[int, int] {
+		return cache.TLRU(10, time.Hour, opts...)
+	})
+}
+
+func TestShardedT(t *testing.T) {
+	t.Run(`add with ttl`, func(t *testing.T) {
+		c := newShardedT()
+
+		c.AddWithTTL(1, 1, cacheDuration)
+		c.Add(2, 2)
+		sleep()
+
+		_, ok := c.Get(1)
+		assert.False(t, ok)
+
+		v, ok := c.Get(2)
+		assert.True(t, ok)
+		assert.Equal(t, 2, v)
+	})
+
+	t.Run(`purge expired in every shard`, func(t *testing.T) {
+		c := newShardedT()
+
+		for key := 0; key < 8; key++ {
+			c.AddWithTTL(key, key, cacheDuration)
+		}
+		sleep()
+		c.PurgeExpired()
+
+		assert.Equal(t, uint64(8), c.Stats().Evictions[cache.ReasonExpired])
+	})
+
+	t.Run(`close stops the janitor of every shard`, func(t *testing.T) {
+		c := newShardedT(cache.WithJanitor[int, int](cacheDuration / 5))
+
+		for key := 0; key < 8; key++ {
+			c.AddWithTTL(key, key, cacheDuration)
+		}
+		c.Close()
+		sleep()
+		sleep()
+
+		assert.Equal(t, uint64(0), c.Stats().Evictions[cache.ReasonExpired])
+		c.Close()
+	})
+}
@@ -0,0 +1,148 @@
+package cache
+
+import (
+	"sync/atomic"
+
+	"github.com/FallenTaters/cache/list"
+)
+
+type sieveEntry[K comparable, V any] struct {
+	keyValue[K, V]
+
+	visited atomic.Bool
+}
+
+// sieve keeps entries in insertion order like fifo, but a hit only sets a visited bit.
+// When evicting, the hand moves from the back to the front, giving visited entries another chance.
+type sieve[K comparable, V any] struct {
+	evictor[K, V]
+
+	maxEntries int
+	entries    *list.List[*sieveEntry[K, V]]
+	values     map[K]*list.Element[*sieveEntry[K, V]]
+
+	hand *list.Element[*sieveEntry[K, V]]
+}
+
+// SIEVE does not move entries on Get, so Get only takes a read lock
+func SIEVE[K comparable, V any](maxEntries int, opts ...Option[K, V]) Cache[K, V] {
+	return newBase[K, V](&sieve[K, V]{
+		maxEntries: maxEntries,
+		entries:    list.New[*sieveEntry[K, V]](),
+		values:     make(map[K]*list.Element[*sieveEntry[K, V]], maxEntries),
+	}, opts)
+}
+
+func (s *sieve[K, V]) get(key K) (V, bool) {
+	e, ok := s.values[key]
+	if !ok {
+		var empty V
+		return empty, false
+	}
+
+	if !e.Value.visited.Load() {
+		e.Value.visited.Store(true)
+	}
+
+	return e.Value.value, true
+}
+
+func (s *sieve[K, V]) peek(key K) (V, bool) {
+	e, ok := s.values[key]
+	if !ok {
+		var empty V
+		return empty, false
+	}
+
+	return e.Value.value, true
+}
+
+func (s *sieve[K, V]) readOnlyGet() bool {
+	return true
+}
+
+func (s *sieve[K, V]) add(key K, value V) {
+	if e, ok := s.values[key]; ok {
+		s.evicted(key, e.Value.value, ReasonReplaced)
+		e.Value.value = value
+		e.Value.visited.Store(true)
+		return
+	}
+
+	if s.maxEntries <= 0 {
+		s.evicted(key, value, ReasonCapacity)
+		return
+	}
+
+	if s.entries.Len() >= s.maxEntries {
+		s.evict()
+	}
+
+	s.values[key] = s.entries.PushFront(&sieveEntry[K, V]{
+		keyValue: keyValue[K, V]{key: key, value: value},
+	})
+}
+
+func (s *sieve[K, V]) remove(key K) (V, bool) {
+	e, ok := s.values[key]
+	if !ok {
+		var empty V
+		return empty, false
+	}
+
+	if s.hand == e {
+		s.hand = e.Prev()
+	}
+
+	s.entries.Remove(e)
+	delete(s.values, key)
+
+	return e.Value.value, true
+}
+
+func (s *sieve[K, V]) resize(maxEntries int) {
+	s.maxEntries = maxEntries
+	for len(s.values) > s.maxEntries {
+		s.evict()
+	}
+}
+
+func (s *sieve[K, V]) walk(fn func(K, V)) {
+	for e := s.entries.Back(); e != nil; e = e.Prev() {
+		fn(e.Value.key, e.Value.value)
+	}
+}
+
+func (s *sieve[K, V]) len() int {
+	return len(s.values)
+}
+
+func (s *sieve[K, V]) name() string {
+	return `sieve`
+}
+
+// evict removes the first unvisited entry from the hand onwards, clearing visited bits on the way
+func (s *sieve[K, V]) evict() {
+	e := s.hand
+	if e == nil {
+		e = s.entries.Back()
+	}
+
+	for e != nil && e.Value.visited.Load() {
+		e.Value.visited.Store(false)
+
+		e = e.Prev()
+		if e == nil {
+			e = s.entries.Back()
+		}
+	}
+
+	if e == nil {
+		return
+	}
+
+	s.hand = e.Prev()
+	s.entries.Remove(e)
+	delete(s.values, e.Value.key)
+	s.evicted(e.Value.key, e.Value.value, ReasonCapacity)
+}
@@ -0,0 +1,136 @@
+package cache_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/FallenTaters/cache"
+	"github.com/FallenTaters/cache/assert"
+)
+
+func newSIEVE() cache.Cache[int, int] {
+	return cache.SIEVE[int, int](2)
+}
+
+func TestSIEVE(t *testing.T) {
+	t.Run(`get non-existing`, func(t *testing.T) {
+		c := newSIEVE()
+
+		v, ok := c.Get(1)
+		assert.False(t, ok)
+		assert.Equal(t, 0, v)
+	})
+
+	t.Run(`add and get`, func(t *testing.T) {
+		c := newSIEVE()
+
+		c.Add(1, 1)
+
+		v, ok := c.Get(1)
+		assert.True(t, ok)
+		assert.Equal(t, 1, v)
+	})
+
+	t.Run(`first in, first out if not visited`, func(t *testing.T) {
+		c := newSIEVE()
+
+		_ = c.MustGetOrAdd(1, newAddFunc(1, nil))
+		_ = c.MustGetOrAdd(2, newAddFunc(2, nil))
+		_ = c.MustGetOrAdd(3, newAddFunc(3, nil))
+
+		v, ok := c.Get(1)
+		assert.Equal(t, 0, v)
+		assert.False(t, ok)
+
+		v, ok = c.Get(2)
+		assert.Equal(t, 2, v)
+		assert.True(t, ok)
+
+		v, ok = c.Get(3)
+		assert.Equal(t, 3, v)
+		assert.True(t, ok)
+	})
+
+	t.Run(`keep visited`, func(t *testing.T) {
+		c := newSIEVE()
+
+		_ = c.MustGetOrAdd(1, newAddFunc(1, nil))
+		_ = c.MustGetOrAdd(2, newAddFunc(2, nil))
+		_, _ = c.Get(1)
+		_ = c.MustGetOrAdd(3, newAddFunc(3, nil))
+
+		v, ok := c.Get(1)
+		assert.Equal(t, 1, v)
+		assert.True(t, ok)
+
+		v, ok = c.Get(2)
+		assert.Equal(t, 0, v)
+		assert.False(t, ok)
+
+		v, ok = c.Get(3)
+		assert.Equal(t, 3, v)
+		assert.True(t, ok)
+	})
+
+	t.Run(`return error`, func(t *testing.T) {
+		myErr := errors.New(`myErr`)
+		c := newSIEVE()
+
+		_, err := c.GetOrAdd(1, newAddFunc(0, myErr))
+		assert.ErrorIs(t, myErr, err)
+	})
+
+	t.Run(`delete`, func(t *testing.T) {
+		c := newSIEVE()
+
+		c.Add(1, 1)
+		c.Add(2, 2)
+		c.Add(3, 3)
+
+		c.Delete(3)
+
+		v, ok := c.Get(3)
+		assert.False(t, ok)
+		assert.Equal(t, 0, v)
+
+		c.Add(4, 4)
+		c.Add(5, 5)
+
+		_, ok = c.Get(5)
+		assert.True(t, ok)
+	})
+
+	t.Run(`concurrent operations`, func(t *testing.T) {
+		c := newSIEVE()
+
+		var wg sync.WaitGroup
+
+		wg.Add(concurrentCount)
+		go func() {
+			for i := 0; i < concurrentCount; i++ {
+				expected := i
+				go func() {
+					actual, err := c.GetOrAdd(expected, newAddFunc(expected, nil))
+					assert.Equal(t, expected, actual)
+					assert.NoError(t, err)
+
+					wg.Done()
+				}()
+			}
+		}()
+
+		wg.Add(concurrentCount)
+		go func() {
+			for i := concurrentCount - 1; i >= 0; i-- {
+				expected := i
+				go func() {
+					c.Delete(expected)
+					wg.Done()
+				}()
+			}
+		}()
+
+		wg.Wait()
+	})
+}
@@ -0,0 +1,85 @@
+package cache
+
+import "hash/maphash"
+
+const (
+	sketchDepth      = 4
+	sketchMaxCounter = 15
+)
+
+// sketch is a count-min sketch estimating how often keys were seen.
+// Counters are halved every sampleSize increments, so old popularity fades.
+type sketch[K comparable] struct {
+	seed maphash.Seed
+
+	mask     uint64
+	counters [sketchDepth][]uint8
+
+	additions  int
+	sampleSize int
+}
+
+func newSketch[K comparable](maxEntries int) *sketch[K] {
+	width := 16
+	for width < maxEntries {
+		width *= 2
+	}
+
+	s := &sketch[K]{
+		seed:       maphash.MakeSeed(),
+		mask:       uint64(width - 1),
+		sampleSize: 10 * width,
+	}
+	for i := range s.counters {
+		s.counters[i] = make([]uint8, width)
+	}
+
+	return s
+}
+
+// increment records an occurrence of key
+func (s *sketch[K]) increment(key K) {
+	h := maphash.Comparable(s.seed, key)
+	for i := range s.counters {
+		idx := s.index(h, i)
+		if s.counters[i][idx] < sketchMaxCounter {
+			s.counters[i][idx]++
+		}
+	}
+
+	s.additions++
+	if s.additions >= s.sampleSize {
+		s.reset()
+	}
+}
+
+// estimate returns the estimated number of occurrences of key
+func (s *sketch[K]) estimate(key K) uint8 {
+	h := maphash.Comparable(s.seed, key)
+
+	lowest := uint8(sketchMaxCounter)
+	for i := range s.counters {
+		if c := s.counters[i][s.index(h, i)]; c < lowest {
+			lowest = c
+		}
+	}
+
+	return lowest
+}
+
+// reset halves all counters
+func (s *sketch[K]) reset() {
+	for i := range s.counters {
+		for j := range s.counters[i] {
+			s.counters[i][j] /= 2
+		}
+	}
+
+	s.additions /= 2
+}
+
+// index derives the counter index for row i from a single hash
+func (s *sketch[K]) index(h uint64, i int) uint64 {
+	h1, h2 := h&0xffffffff, h>>32
+	return (h1 + uint64(i)*h2) & s.mask
+}
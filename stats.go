@@ -0,0 +1,136 @@
+package cache
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// Stats holds the counters of a cache since it was created or since ResetStats was called
+type Stats struct {
+	Hits   uint64
+	Misses uint64
+
+	// Loads is the number of addFunc calls that succeeded, LoadErrors the number that failed
+	Loads      uint64
+	LoadErrors uint64
+
+	// LoadTime is the total time spent in addFunc calls
+	LoadTime time.Duration
+
+	// Evictions is the number of entries that left the cache, by reason
+	Evictions map[EvictionReason]uint64
+
+	// Entries is the current number of entries, like Len
+	Entries int
+}
+
+// HitRatio returns the fraction of lookups that were hits, or 0 if there were none
+func (s Stats) HitRatio() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+
+	return float64(s.Hits) / float64(total)
+}
+
+// add returns the sum of s and other
+func (s Stats) add(other Stats) Stats {
+	sum := Stats{
+		Hits:       s.Hits + other.Hits,
+		Misses:     s.Misses + other.Misses,
+		Loads:      s.Loads + other.Loads,
+		LoadErrors: s.LoadErrors + other.LoadErrors,
+		LoadTime:   s.LoadTime + other.LoadTime,
+		Evictions:  make(map[EvictionReason]uint64, numReasons),
+		Entries:    s.Entries + other.Entries,
+	}
+
+	for reason := EvictionReason(0); reason < numReasons; reason++ {
+		sum.Evictions[reason] = s.Evictions[reason] + other.Evictions[reason]
+	}
+
+	return sum
+}
+
+// stats are updated atomically, so they can be updated without holding the cache lock
+type stats struct {
+	hits       atomic.Uint64
+	misses     atomic.Uint64
+	loads      atomic.Uint64
+	loadErrors atomic.Uint64
+	loadTime   atomic.Int64
+	evictions  [numReasons]atomic.Uint64
+}
+
+func (s *stats) lookup(hit bool) {
+	if hit {
+		s.hits.Add(1)
+	} else {
+		s.misses.Add(1)
+	}
+}
+
+func (s *stats) evicted(reason EvictionReason) {
+	s.evictions[reason].Add(1)
+}
+
+func (s *stats) load(start time.Time, failed bool) {
+	s.loadTime.Add(int64(time.Since(start)))
+
+	if failed {
+		s.loadErrors.Add(1)
+	} else {
+		s.loads.Add(1)
+	}
+}
+
+func (s *stats) snapshot() Stats {
+	snapshot := Stats{
+		Hits:       s.hits.Load(),
+		Misses:     s.misses.Load(),
+		Loads:      s.loads.Load(),
+		LoadErrors: s.loadErrors.Load(),
+		LoadTime:   time.Duration(s.loadTime.Load()),
+		Evictions:  make(map[EvictionReason]uint64, numReasons),
+	}
+
+	for reason := range s.evictions {
+		snapshot.Evictions[EvictionReason(reason)] = s.evictions[reason].Load()
+	}
+
+	return snapshot
+}
+
+func (s *stats) reset() {
+	s.hits.Store(0)
+	s.misses.Store(0)
+	s.loads.Store(0)
+	s.loadErrors.Store(0)
+	s.loadTime.Store(0)
+
+	for reason := range s.evictions {
+		s.evictions[reason].Store(0)
+	}
+}
+
+// timed wraps addFunc to record its duration and result in s, a panic counts as an error
+func timed[V any](s *stats, addFunc AddFuncCtx[V]) AddFuncCtx[V] {
+	return func(ctx context.Context) (V, error) {
+		start := time.Now()
+
+		returned := false
+		defer func() {
+			if !returned {
+				s.load(start, true)
+			}
+		}()
+
+		v, err := addFunc(ctx)
+		returned = true
+		s.load(start, err != nil)
+
+		return v, err
+	}
+}
@@ -0,0 +1,124 @@
+package cache_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/FallenTaters/cache"
+	"github.com/FallenTaters/cache/assert"
+)
+
+func TestStats(t *testing.T) {
+	t.Run(`hits and misses`, func(t *testing.T) {
+		c := newLRU()
+
+		c.Add(1, 1)
+		_, _ = c.Get(1)
+		_, _ = c.Get(1)
+		_, _ = c.Get(2)
+
+		s := c.Stats()
+		assert.Equal(t, uint64(2), s.Hits)
+		assert.Equal(t, uint64(1), s.Misses)
+		assert.Equal(t, 2.0/3.0, s.HitRatio())
+		assert.Equal(t, 1, s.Entries)
+	})
+
+	t.Run(`loads`, func(t *testing.T) {
+		myErr := errors.New(`myErr`)
+		c := newFIFO()
+
+		_ = c.MustGetOrAdd(1, func() (int, error) {
+			time.Sleep(cacheDuration)
+			return 1, nil
+		})
+		_ = c.MustGetOrAdd(1, newAddFunc(1, nil))
+		_, _ = c.GetOrAdd(2, newAddFunc(0, myErr))
+		_, _ = c.GetOrAdd(3, func() (int, error) {
+			panic(`oops`)
+		})
+
+		s := c.Stats()
+		assert.Equal(t, uint64(1), s.Hits)
+		assert.Equal(t, uint64(3), s.Misses)
+		assert.Equal(t, uint64(1), s.Loads)
+		assert.Equal(t, uint64(2), s.LoadErrors)
+		assert.True(t, s.LoadTime >= cacheDuration)
+	})
+
+	t.Run(`evictions`, func(t *testing.T) {
+		c := newFIFO()
+
+		c.Add(1, 1)
+		c.Add(1, 1)
+		c.Add(2, 2)
+		c.Add(3, 3)
+		c.Delete(3)
+
+		s := c.Stats()
+		assert.Equal(t, uint64(1), s.Evictions[cache.ReasonReplaced])
+		assert.Equal(t, uint64(1), s.Evictions[cache.ReasonCapacity])
+		assert.Equal(t, uint64(1), s.Evictions[cache.ReasonDeleted])
+		assert.Equal(t, uint64(0), s.Evictions[cache.ReasonExpired])
+		assert.Equal(t, 1, s.Entries)
+	})
+
+	t.Run(`expired entries are misses`, func(t *testing.T) {
+		c := newTLRU()
+
+		c.Add(1, 1)
+		_, _ = c.Get(1)
+		sleep()
+		_ = c.MustGetOrAdd(1, newAddFunc(1, nil))
+
+		s := c.Stats()
+		assert.Equal(t, uint64(1), s.Hits)
+		assert.Equal(t, uint64(1), s.Misses)
+		assert.Equal(t, uint64(1), s.Loads)
+		assert.Equal(t, uint64(1), s.Evictions[cache.ReasonExpired])
+		assert.Equal(t, 1, s.Entries)
+	})
+
+	t.Run(`expired entries are not counted`, func(t *testing.T) {
+		c := newTLRU()
+
+		c.Add(1, 1)
+		c.AddWithTTL(2, 2, time.Hour)
+		sleep()
+
+		assert.Equal(t, 1, c.Len())
+		assert.Equal(t, c.Len(), c.Stats().Entries)
+	})
+
+	t.Run(`sharded stats are summed`, func(t *testing.T) {
+		c := newSharded()
+
+		for key := 0; key < 4; key++ {
+			_ = c.MustGetOrAdd(key, newAddFunc(key, nil))
+			_, _ = c.Get(key)
+		}
+
+		s := c.Stats()
+		assert.Equal(t, uint64(4), s.Hits)
+		assert.Equal(t, uint64(4), s.Misses)
+		assert.Equal(t, uint64(4), s.Loads)
+		assert.Equal(t, 4, s.Entries+int(s.Evictions[cache.ReasonCapacity]))
+	})
+
+	t.Run(`reset`, func(t *testing.T) {
+		c := newTFIFO()
+
+		_ = c.MustGetOrAdd(1, newAddFunc(1, nil))
+		_, _ = c.Get(1)
+
+		c.ResetStats()
+
+		s := c.Stats()
+		assert.Equal(t, uint64(0), s.Hits)
+		assert.Equal(t, uint64(0), s.Misses)
+		assert.Equal(t, uint64(0), s.Loads)
+		assert.Equal(t, time.Duration(0), s.LoadTime)
+		assert.Equal(t, 1, s.Entries)
+	})
+}
@@ -1,31 +1,176 @@
 package cache
 
-import "time"
+import (
+	"cmp"
+	"context"
+	"io"
+	"iter"
+	"math"
+	"math/rand/v2"
+	"time"
+)
 
-func TLRU[K comparable, V any](maxEntries int, maxAge time.Duration) Cache[K, V] {
-	return tCache[K, V]{
-		cache:  LRU[K, addedValue[V]](maxEntries).(*lru[K, addedValue[V]]),
+func TLRU[K comparable, V any](maxEntries int, maxAge time.Duration, opts ...Option[K, V]) TCache[K, V] {
+	return newTCache(LRU(maxEntries, innerOptions(opts)...), maxAge, opts)
+}
+
+func TFIFO[K comparable, V any](maxEntries int, maxAge time.Duration, opts ...Option[K, V]) TCache[K, V] {
+	return newTCache(FIFO(maxEntries, innerOptions(opts)...), maxAge, opts)
+}
+
+func TLFU[K comparable, V any](maxEntries int, maxAge time.Duration, opts ...Option[K, V]) TCache[K, V] {
+	return newTCache(LFU(maxEntries, innerOptions(opts)...), maxAge, opts)
+}
+
+func newTCache[K comparable, V any](c Cache[K, addedValue[V]], maxAge time.Duration, opts []Option[K, V]) tCache[K, V] {
+	o := newOptions(opts)
+
+	t := tCache[K, V]{
+		cache:  c.(cache[K, addedValue[V]]),
 		maxAge: maxAge,
+		grace:  o.staleWhileRevalidate,
+		stats:  &stats{},
+		codec:  o.codec,
+
+		staleIfError: o.staleIfError,
+		onStaleError: o.onStaleError,
+		refreshAhead: o.refreshAhead,
+		beta:         o.earlyExpiration,
+
+		errorTTL:  o.errorTTL,
+		cacheable: o.cacheableError,
+	}
+
+	if o.janitorInterval > 0 {
+		t.janitor = startJanitor(o.janitorInterval, t.PurgeExpired)
 	}
+
+	return t
 }
 
-func TFIFO[K comparable, V any](maxEntries int, maxAge time.Duration) Cache[K, V] {
-	return tCache[K, V]{
-		cache:  FIFO[K, addedValue[V]](maxEntries).(*fifo[K, addedValue[V]]),
-		maxAge: maxAge,
+// innerOptions converts the options of a tCache to options for the cache it wraps
+func innerOptions[K comparable, V any](opts []Option[K, V]) []Option[K, addedValue[V]] {
+	o := newOptions(opts)
+
+	inner := []Option[K, addedValue[V]]{
+		func(inner *options[K, addedValue[V]]) {
+			inner.expired = addedValue[V].expired
+			// cached errors are not values, so they are not reported when they leave the cache
+			inner.hidden = func(v addedValue[V]) bool {
+				return v.err != nil
+			}
+		},
+	}
+
+	if o.onEvict != nil {
+		inner = append(inner, OnEvict(func(key K, v addedValue[V], reason EvictionReason) {
+			o.onEvict(key, v.value, reason)
+		}))
 	}
+
+	return inner
 }
 
 type addedValue[V any] struct {
-	value V
-	added time.Time
+	value  V
+	added  time.Time
+	maxAge time.Duration
+
+	// addFunc is the addFunc that value was loaded with, or nil if it was added directly
+	addFunc AddFuncCtx[addedValue[V]]
+
+	// loadTime is how long addFunc took to load value
+	loadTime time.Duration
+
+	// err is a cached error of addFunc, which is returned by GetOrAdd instead of value
+	err error
+}
+
+func (v addedValue[V]) expired() bool {
+	return v.expiredFor(0)
 }
 
-func wrapAddFunc[V any](addFunc AddFunc[V]) AddFunc[addedValue[V]] {
-	return func() (addedValue[V], error) {
-		v, err := addFunc()
-		return addedValue[V]{value: v, added: time.Now()}, err
+// expiredFor returns true if v expired more than d ago
+func (v addedValue[V]) expiredFor(d time.Duration) bool {
+	return time.Since(v.added) > v.maxAge+d
+}
+
+// refreshDue returns true if v can be reloaded and is in the last fraction of its maxAge
+func (v addedValue[V]) refreshDue(fraction float64) bool {
+	return v.addFunc != nil && time.Since(v.added) > time.Duration(float64(v.maxAge)*(1-fraction))
+}
+
+// expiresEarly returns true with a probability that rises as v gets closer to expiring, and with its loadTime,
+// as described in "Optimal Probabilistic Cache Stampede Prevention" by Vattani et al.
+func (v addedValue[V]) expiresEarly(beta float64) bool {
+	if v.addFunc == nil {
+		return false
 	}
+
+	early := time.Duration(float64(v.loadTime) * beta * -math.Log(1-rand.Float64()))
+	return time.Since(v.added)+early > v.maxAge
+}
+
+func wrapAddFunc[V any](addFunc AddFuncCtx[V], maxAge time.Duration) AddFuncCtx[addedValue[V]] {
+	var wrapped AddFuncCtx[addedValue[V]]
+	wrapped = func(ctx context.Context) (addedValue[V], error) {
+		start := time.Now()
+		v, err := addFunc(ctx)
+		return addedValue[V]{value: v, added: time.Now(), maxAge: maxAge, addFunc: wrapped, loadTime: time.Since(start)}, err
+	}
+
+	return wrapped
+}
+
+func wrapBatchAddFunc[K comparable, V any](addFunc BatchAddFunc[K, V], maxAge time.Duration) batchAddFuncCtx[K, addedValue[V]] {
+	return func(_ context.Context, missing []K) (map[K]addedValue[V], error) {
+		start := time.Now()
+		values, err := addFunc(missing)
+		now := time.Now()
+
+		added := make(map[K]addedValue[V], len(values))
+		for key, v := range values {
+			added[key] = addedValue[V]{
+				value:    v,
+				added:    now,
+				maxAge:   maxAge,
+				addFunc:  wrapBatchReload(key, addFunc, maxAge),
+				loadTime: now.Sub(start),
+			}
+		}
+
+		return added, err
+	}
+}
+
+// wrapBatchReload reloads key on its own with addFunc, so values loaded in a batch can be refreshed
+func wrapBatchReload[K comparable, V any](key K, addFunc BatchAddFunc[K, V], maxAge time.Duration) AddFuncCtx[addedValue[V]] {
+	return wrapAddFunc(func(context.Context) (V, error) {
+		var empty V
+
+		values, err := addFunc([]K{key})
+		if err != nil {
+			return empty, err
+		}
+
+		v, ok := values[key]
+		if !ok {
+			return empty, ErrMissingKey
+		}
+
+		return v, nil
+	}, maxAge)
+}
+
+func wrapTTLAddFunc[V any](addFunc TTLAddFunc[V]) AddFuncCtx[addedValue[V]] {
+	var wrapped AddFuncCtx[addedValue[V]]
+	wrapped = func(context.Context) (addedValue[V], error) {
+		start := time.Now()
+		v, maxAge, err := addFunc()
+		return addedValue[V]{value: v, added: time.Now(), maxAge: maxAge, addFunc: wrapped, loadTime: time.Since(start)}, err
+	}
+
+	return wrapped
 }
 
 type cache[K comparable, V any] interface {
@@ -35,12 +180,42 @@ type cache[K comparable, V any] interface {
 	RUnlock()
 	Lock()
 	Unlock()
+
+	deleteIf(func(V) bool, EvictionReason, ...K)
+	deleteFunc(func(K, V) bool, EvictionReason)
+	refresh(K, AddFuncCtx[V])
+	load(context.Context, K, AddFuncCtx[V]) (V, error)
+	loadMany(context.Context, []K, batchAddFuncCtx[K, V], func([]K)) (map[K]V, error)
+	restore([]entry[K, V])
+	watch(func(K)) bool
 }
 
 type tCache[K comparable, V any] struct {
 	cache[K, addedValue[V]]
 
-	maxAge time.Duration
+	maxAge  time.Duration
+	janitor *janitor
+	codec   Codec
+
+	// grace is how long expired values are still returned by GetOrAdd while they are refreshed
+	grace time.Duration
+
+	// staleIfError is how long expired values are still returned by GetOrAdd if addFunc fails
+	staleIfError time.Duration
+	onStaleError func(K, error)
+
+	// refreshAhead is the fraction of maxAge before expiry in which a hit reloads the value
+	refreshAhead float64
+
+	// beta weighs the loadTime of values for early expiration
+	beta float64
+
+	// errorTTL is how long errors of addFunc are cached, if cacheable returns true for them
+	errorTTL  time.Duration
+	cacheable func(error) bool
+
+	// stats counts hits and misses, because the wrapped cache counts expired entries as hits
+	stats *stats
 }
 
 func (t tCache[K, V]) Get(key K) (V, bool) {
@@ -48,41 +223,137 @@ func (t tCache[K, V]) Get(key K) (V, bool) {
 
 	v, ok := t.cache.Get(key)
 	if !ok {
+		t.stats.lookup(false)
 		return empty, false
 	}
 
-	if time.Since(v.added) > t.maxAge {
-		t.Delete(key)
+	if v.expired() {
+		t.stats.lookup(false)
+		t.cache.deleteIf(t.removable, ReasonExpired, key)
 		return empty, false
 	}
 
+	if v.err != nil {
+		t.stats.lookup(false)
+		return empty, false
+	}
+
+	t.stats.lookup(true)
+	t.refreshIfDue(key, v)
 	return v.value, true
 }
 
+// GetMany returns the values that are found and not expired, like Get
+func (t tCache[K, V]) GetMany(keys []K) map[K]V {
+	var expired []K
+	values := make(map[K]V, len(keys))
+	for key, v := range t.cache.GetMany(keys) {
+		if v.expired() {
+			expired = append(expired, key)
+			continue
+		}
+
+		if v.err == nil {
+			values[key] = v.value
+			t.refreshIfDue(key, v)
+		}
+	}
+
+	for _, key := range keys {
+		_, ok := values[key]
+		t.stats.lookup(ok)
+	}
+
+	if len(expired) > 0 {
+		t.cache.deleteIf(t.removable, ReasonExpired, expired...)
+	}
+
+	return values
+}
+
 func (t tCache[K, V]) Add(key K, value V) {
+	t.AddWithTTL(key, value, t.maxAge)
+}
+
+func (t tCache[K, V]) AddWithTTL(key K, value V, ttl time.Duration) {
 	t.cache.Add(key, addedValue[V]{
-		value: value,
-		added: time.Now(),
+		value:  value,
+		added:  time.Now(),
+		maxAge: ttl,
 	})
 }
 
-func (t tCache[K, V]) GetOrAdd(key K, addFunc AddFunc[V]) (V, error) {
-	var empty V
-	wrappedAddFunc := wrapAddFunc(addFunc)
+func (t tCache[K, V]) AddMany(values map[K]V) {
+	now := time.Now()
 
-	v, err := t.cache.GetOrAdd(key, wrappedAddFunc)
-	if err != nil {
-		return empty, err
+	added := make(map[K]addedValue[V], len(values))
+	for key, value := range values {
+		added[key] = addedValue[V]{
+			value:  value,
+			added:  now,
+			maxAge: t.maxAge,
+		}
 	}
 
-	if time.Since(v.added) <= t.maxAge {
-		return v.value, nil
+	t.cache.AddMany(added)
+}
+
+// Compute is like Compute of the wrapped cache, expired values are passed to fn as not found.
+// A stored value expires after the default maxAge
+func (t tCache[K, V]) Compute(key K, fn ComputeFunc[V]) (V, bool) {
+	var empty V
+
+	v, ok := t.cache.Compute(key, func(old addedValue[V], found bool) (addedValue[V], Action) {
+		if old.expired() || old.err != nil {
+			old.value, found = empty, false
+		}
+
+		value, action := fn(old.value, found)
+		if action != ActionStore {
+			return old, action
+		}
+
+		return addedValue[V]{
+			value:  value,
+			added:  time.Now(),
+			maxAge: t.maxAge,
+		}, action
+	})
+	if !ok || v.expired() || v.err != nil {
+		return empty, false
 	}
 
-	t.Delete(key)
-	v, err = t.cache.GetOrAdd(key, wrappedAddFunc)
+	return v.value, true
+}
+
+func (t tCache[K, V]) AddIfAbsent(key K, value V) bool {
+	added := false
+	t.Compute(key, func(old V, found bool) (V, Action) {
+		if found {
+			return old, ActionKeep
+		}
 
-	return v.value, err
+		added = true
+		return value, ActionStore
+	})
+
+	return added
+}
+
+func (t tCache[K, V]) GetOrAdd(key K, addFunc AddFunc[V]) (V, error) {
+	return t.getOrAdd(context.Background(), key, wrapAddFunc(addFunc.withContext(), t.maxAge))
+}
+
+func (t tCache[K, V]) GetOrAddCtx(ctx context.Context, key K, addFunc AddFuncCtx[V]) (V, error) {
+	return t.getOrAdd(ctx, key, wrapAddFunc(addFunc, t.maxAge))
+}
+
+func (t tCache[K, V]) GetOrAddWithTTL(key K, addFunc AddFunc[V], ttl time.Duration) (V, error) {
+	return t.getOrAdd(context.Background(), key, wrapAddFunc(addFunc.withContext(), ttl))
+}
+
+func (t tCache[K, V]) GetOrAddTTLFunc(key K, addFunc TTLAddFunc[V]) (V, error) {
+	return t.getOrAdd(context.Background(), key, wrapTTLAddFunc(addFunc))
 }
 
 func (t tCache[K, V]) MustGetOrAdd(key K, addFunc AddFunc[V]) V {
@@ -93,3 +364,284 @@ func (t tCache[K, V]) MustGetOrAdd(key K, addFunc AddFunc[V]) V {
 
 	return v
 }
+
+// GetOrAddMany is like GetOrAdd for many keys, but expired values are always reloaded
+// and errors of addFunc are not cached
+func (t tCache[K, V]) GetOrAddMany(keys []K, addFunc BatchAddFunc[K, V]) (map[K]V, error) {
+	return t.getOrAddMany(keys, addFunc, nil)
+}
+
+// getOrAddMany is GetOrAddMany, but if claimed is not nil, it gets the keys that will be passed to addFunc
+// before getOrAddMany waits for anything
+func (t tCache[K, V]) getOrAddMany(keys []K, addFunc BatchAddFunc[K, V], claimed func([]K)) (map[K]V, error) {
+	var err error
+	var missing []K
+	values := make(map[K]V, len(keys))
+
+	found := t.cache.GetMany(keys)
+	for _, key := range keys {
+		v, ok := found[key]
+		if !ok || v.expired() {
+			t.stats.lookup(false)
+			missing = append(missing, key)
+			continue
+		}
+
+		// a cached error is a miss, like in Get
+		t.stats.lookup(v.err == nil)
+		if v.err != nil {
+			err = cmp.Or(err, v.err)
+			continue
+		}
+
+		values[key] = v.value
+		t.refreshIfDue(key, v)
+	}
+
+	if len(missing) == 0 {
+		if claimed != nil {
+			claimed(nil)
+		}
+		return values, err
+	}
+
+	t.cache.deleteIf(t.removable, ReasonExpired, missing...)
+
+	loaded, loadErr := t.cache.loadMany(context.Background(), missing, wrapBatchAddFunc(addFunc, t.maxAge), claimed)
+	for key, v := range loaded {
+		// a key can share a running GetOrAdd, which may have cached an error
+		if v.err != nil {
+			err = cmp.Or(err, v.err)
+			continue
+		}
+
+		values[key] = v.value
+	}
+
+	return values, cmp.Or(err, loadErr)
+}
+
+func (t tCache[K, V]) getOrAdd(ctx context.Context, key K, wrappedAddFunc AddFuncCtx[addedValue[V]]) (V, error) {
+	var empty V
+
+	v, ok := t.cache.Get(key)
+	if ok && !v.expired() {
+		t.stats.lookup(v.err == nil)
+		if v.err != nil {
+			return empty, v.err
+		}
+
+		t.refreshIfDue(key, v)
+		return v.value, nil
+	}
+
+	stale := ok && v.err == nil
+	if stale && !v.expiredFor(t.grace) {
+		t.stats.lookup(true)
+		t.cache.refresh(key, wrappedAddFunc)
+		return v.value, nil
+	}
+
+	t.stats.lookup(false)
+	if stale && !v.expiredFor(t.staleIfError) {
+		return t.loadOrStale(ctx, key, v, wrappedAddFunc)
+	}
+
+	if ok {
+		t.cache.deleteIf(t.removable, ReasonExpired, key)
+	}
+
+	v, err := t.cache.GetOrAddCtx(ctx, key, t.cacheErrors(wrappedAddFunc))
+	if err != nil {
+		return empty, err
+	}
+
+	if v.err != nil {
+		return empty, v.err
+	}
+
+	return v.value, nil
+}
+
+// Len returns the number of entries that are not expired, it has to check every entry
+func (t tCache[K, V]) Len() int {
+	n := 0
+	for range t.All() {
+		n++
+	}
+
+	return n
+}
+
+func (t tCache[K, V]) Peek(key K) (V, bool) {
+	v, ok := t.cache.Peek(key)
+	if !ok || v.expired() || v.err != nil {
+		var empty V
+		return empty, false
+	}
+
+	return v.value, true
+}
+
+// Keys returns the keys of entries that are not expired, in eviction order
+func (t tCache[K, V]) Keys() []K {
+	var keys []K
+	for key := range t.All() {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+// All iterates over the entries that are not expired, in eviction order
+func (t tCache[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for key, v := range t.cache.All() {
+			if v.expired() || v.err != nil {
+				continue
+			}
+
+			if !yield(key, v.value) {
+				return
+			}
+		}
+	}
+}
+
+// Save also writes when each entry was added and its maximum age, cached errors are not saved
+func (t tCache[K, V]) Save(w io.Writer) error {
+	return encodeEntries(t.codec, w, t.entries())
+}
+
+// Load keeps the time at which entries were added, so they expire at the same time as in the saved cache.
+// Entries that have expired since are not added
+func (t tCache[K, V]) Load(r io.Reader) error {
+	entries, err := decodeEntries[K, V](t.codec, r)
+	if err != nil {
+		return err
+	}
+
+	t.restore(entries)
+	return nil
+}
+
+func (t tCache[K, V]) entries() []entry[K, V] {
+	var entries []entry[K, V]
+	for key, v := range t.cache.All() {
+		if v.err != nil {
+			continue
+		}
+
+		entries = append(entries, entry[K, V]{Key: key, Value: v.value, Added: v.added, MaxAge: v.maxAge})
+	}
+
+	return entries
+}
+
+// peekEntry also returns expired entries, they are left out when they are restored
+func (t tCache[K, V]) peekEntry(key K) (entry[K, V], bool) {
+	v, ok := t.cache.Peek(key)
+	if !ok || v.err != nil {
+		return entry[K, V]{Key: key}, false
+	}
+
+	return entry[K, V]{Key: key, Value: v.value, Added: v.added, MaxAge: v.maxAge}, true
+}
+
+func (t tCache[K, V]) restore(entries []entry[K, V]) {
+	added := make([]entry[K, addedValue[V]], 0, len(entries))
+	for _, e := range entries {
+		v := addedValue[V]{value: e.Value, added: e.Added, maxAge: e.MaxAge}
+		if t.removable(v) {
+			continue
+		}
+
+		added = append(added, entry[K, addedValue[V]]{Key: e.Key, Value: v})
+	}
+
+	t.cache.restore(added)
+}
+
+func (t tCache[K, V]) Stats() Stats {
+	// own is taken first, because cacheErrors counts an error before the wrapped cache counts the load
+	own := t.stats.snapshot()
+	s := t.cache.Stats()
+
+	s.Hits = own.Hits
+	s.Misses = own.Misses
+	// the wrapped cache also counts expired entries and cached errors
+	s.Entries = t.Len()
+	s.Loads -= min(s.Loads, own.LoadErrors)
+	s.LoadErrors += own.LoadErrors
+
+	return s
+}
+
+func (t tCache[K, V]) ResetStats() {
+	t.cache.ResetStats()
+	t.stats.reset()
+}
+
+func (t tCache[K, V]) Policy() string {
+	return `t` + t.cache.Policy()
+}
+
+// Close stops the janitor, if there is one
+func (t tCache[K, V]) Close() {
+	t.janitor.Close()
+}
+
+// PurgeExpired keeps expired entries that can still be returned as stale values
+func (t tCache[K, V]) PurgeExpired() {
+	t.cache.deleteFunc(func(_ K, v addedValue[V]) bool {
+		return t.removable(v)
+	}, ReasonExpired)
+}
+
+// refreshIfDue reloads v in the background if it is close enough to expiring
+func (t tCache[K, V]) refreshIfDue(key K, v addedValue[V]) {
+	if t.refreshAhead > 0 && v.refreshDue(t.refreshAhead) || t.beta > 0 && v.expiresEarly(t.beta) {
+		t.cache.refresh(key, v.addFunc)
+	}
+}
+
+// cacheErrors makes addFunc return cacheable errors as values, so they are added to the cache
+func (t tCache[K, V]) cacheErrors(addFunc AddFuncCtx[addedValue[V]]) AddFuncCtx[addedValue[V]] {
+	if t.errorTTL <= 0 {
+		return addFunc
+	}
+
+	return func(ctx context.Context) (addedValue[V], error) {
+		v, err := addFunc(ctx)
+		if err == nil || t.cacheable != nil && !t.cacheable(err) {
+			return v, err
+		}
+
+		// the wrapped cache counts this as a successful load, which Stats corrects
+		t.stats.loadErrors.Add(1)
+		return addedValue[V]{err: err, added: time.Now(), maxAge: t.errorTTL}, nil
+	}
+}
+
+// loadOrStale loads a new value for key, but returns the stale value if that fails
+func (t tCache[K, V]) loadOrStale(ctx context.Context, key K, stale addedValue[V], wrappedAddFunc AddFuncCtx[addedValue[V]]) (V, error) {
+	v, err := t.cache.load(ctx, key, wrappedAddFunc)
+	if err == nil {
+		return v.value, nil
+	}
+
+	if t.onStaleError != nil {
+		t.onStaleError(key, err)
+	}
+
+	return stale.value, nil
+}
+
+// removable returns true if v is expired and can no longer be returned as a stale value
+func (t tCache[K, V]) removable(v addedValue[V]) bool {
+	if v.err != nil {
+		return v.expired()
+	}
+
+	return v.expiredFor(max(t.grace, t.staleIfError))
+}
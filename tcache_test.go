@@ -2,6 +2,7 @@ package cache_test
 
 import (
 	"errors"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -11,11 +12,11 @@ import (
 
 const cacheDuration = 10 * time.Millisecond
 
-func newTFIFO() cache.Cache[int, int] {
+func newTFIFO() cache.TCache[int, int] {
 	return cache.TFIFO[int, int](3, cacheDuration)
 }
 
-func newTLRU() cache.Cache[int, int] {
+func newTLRU() cache.TCache[int, int] {
 	return cache.TLRU[int, int](3, cacheDuration)
 }
 
@@ -85,3 +86,463 @@ func TestTFIFO(t *testing.T) {
 		assert.True(t, *called)
 	})
 }
+
+func TestTLFU(t *testing.T) {
+	t.Run(`expiring`, func(t *testing.T) {
+		c := cache.TLFU[int, int](3, cacheDuration)
+
+		c.Add(1, 1)
+		_, ok := c.Get(1)
+		assert.True(t, ok)
+
+		sleep()
+
+		_, ok = c.Get(1)
+		assert.False(t, ok)
+	})
+}
+
+func TestTTL(t *testing.T) {
+	t.Run(`add with ttl`, func(t *testing.T) {
+		c := cache.TLRU[int, int](3, time.Hour)
+
+		c.AddWithTTL(1, 1, cacheDuration)
+		c.Add(2, 2)
+
+		v, ok := c.Get(1)
+		assert.True(t, ok)
+		assert.Equal(t, 1, v)
+
+		sleep()
+
+		_, ok = c.Get(1)
+		assert.False(t, ok)
+
+		v, ok = c.Get(2)
+		assert.True(t, ok)
+		assert.Equal(t, 2, v)
+	})
+
+	t.Run(`get or add with ttl`, func(t *testing.T) {
+		c := cache.TFIFO[int, int](3, time.Hour)
+
+		addFunc, called := calledAddFunc(1, nil)
+
+		_, err := c.GetOrAddWithTTL(1, addFunc, cacheDuration)
+		assert.NoError(t, err)
+		assert.True(t, *called)
+
+		*called = false
+		_, err = c.GetOrAddWithTTL(1, addFunc, cacheDuration)
+		assert.NoError(t, err)
+		assert.False(t, *called)
+
+		sleep()
+		_, err = c.GetOrAddWithTTL(1, addFunc, cacheDuration)
+		assert.NoError(t, err)
+		assert.True(t, *called)
+	})
+
+	t.Run(`ttl from addFunc`, func(t *testing.T) {
+		c := cache.TLRU[int, int](3, time.Hour)
+
+		v, err := c.GetOrAddTTLFunc(1, func() (int, time.Duration, error) {
+			return 1, cacheDuration, nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, v)
+
+		_, ok := c.Get(1)
+		assert.True(t, ok)
+
+		sleep()
+
+		_, ok = c.Get(1)
+		assert.False(t, ok)
+	})
+
+	t.Run(`ttl func error`, func(t *testing.T) {
+		myErr := errors.New(`myErr`)
+		c := cache.TLRU[int, int](3, time.Hour)
+
+		_, err := c.GetOrAddTTLFunc(1, func() (int, time.Duration, error) {
+			return 0, 0, myErr
+		})
+		assert.ErrorIs(t, myErr, err)
+	})
+}
+
+func TestJanitor(t *testing.T) {
+	t.Run(`expired entries do not push out valid ones`, func(t *testing.T) {
+		c := cache.TFIFO(2, cacheDuration, cache.WithJanitor[int, int](cacheDuration/5))
+		defer c.Close()
+
+		c.AddWithTTL(1, 1, time.Hour)
+		c.Add(2, 2)
+
+		sleep()
+		sleep()
+
+		c.Add(3, 3)
+
+		v, ok := c.Get(1)
+		assert.True(t, ok)
+		assert.Equal(t, 1, v)
+
+		v, ok = c.Get(3)
+		assert.True(t, ok)
+		assert.Equal(t, 3, v)
+	})
+
+	t.Run(`close without janitor`, func(t *testing.T) {
+		c := newTLRU()
+		c.Close()
+		c.Close()
+	})
+}
+
+func TestStaleWhileRevalidate(t *testing.T) {
+	t.Run(`return stale value and refresh in background`, func(t *testing.T) {
+		c := cache.TLRU(3, cacheDuration, cache.WithStaleWhileRevalidate[int, int](time.Hour))
+
+		c.Add(1, 1)
+		sleep()
+
+		var count atomic.Int32
+		refreshed := make(chan struct{})
+		addFunc := func() (int, error) {
+			count.Add(1)
+			<-refreshed
+			return 2, nil
+		}
+
+		for i := 0; i < 10; i++ {
+			v, err := c.GetOrAdd(1, addFunc)
+			assert.NoError(t, err)
+			assert.Equal(t, 1, v)
+		}
+
+		_, ok := c.Get(1)
+		assert.False(t, ok, `Get does not return stale values`)
+
+		close(refreshed)
+		time.Sleep(cacheDuration / 2)
+
+		v, ok := c.Get(1)
+		assert.True(t, ok)
+		assert.Equal(t, 2, v)
+		assert.Equal(t, int32(1), count.Load())
+	})
+
+	t.Run(`keep stale value if refresh fails`, func(t *testing.T) {
+		myErr := errors.New(`myErr`)
+		c := cache.TFIFO(3, cacheDuration, cache.WithStaleWhileRevalidate[int, int](time.Hour))
+
+		c.Add(1, 1)
+		sleep()
+
+		v, err := c.GetOrAdd(1, newAddFunc(0, myErr))
+		assert.NoError(t, err)
+		assert.Equal(t, 1, v)
+
+		time.Sleep(cacheDuration / 2)
+
+		v, err = c.GetOrAdd(1, newAddFunc(2, nil))
+		assert.NoError(t, err)
+		assert.Equal(t, 1, v)
+	})
+
+	t.Run(`load after grace`, func(t *testing.T) {
+		c := cache.TLRU(3, cacheDuration, cache.WithStaleWhileRevalidate[int, int](cacheDuration))
+
+		c.Add(1, 1)
+		sleep()
+		sleep()
+
+		v, err := c.GetOrAdd(1, newAddFunc(2, nil))
+		assert.NoError(t, err)
+		assert.Equal(t, 2, v)
+	})
+}
+
+func TestStaleIfError(t *testing.T) {
+	t.Run(`return stale value if addFunc fails`, func(t *testing.T) {
+		myErr := errors.New(`myErr`)
+		var gotKey int
+		var gotErr error
+		c := cache.TLRU(3, cacheDuration, cache.WithStaleIfError[int, int](time.Hour, func(key int, err error) {
+			gotKey, gotErr = key, err
+		}))
+
+		c.Add(1, 1)
+		sleep()
+
+		v, err := c.GetOrAdd(1, newAddFunc(0, myErr))
+		assert.NoError(t, err)
+		assert.Equal(t, 1, v)
+		assert.Equal(t, 1, gotKey)
+		assert.ErrorIs(t, myErr, gotErr)
+
+		_, ok := c.Get(1)
+		assert.False(t, ok, `Get does not return stale values`)
+	})
+
+	t.Run(`replace stale value if addFunc succeeds`, func(t *testing.T) {
+		c := cache.TFIFO(3, cacheDuration, cache.WithStaleIfError[int, int](time.Hour, nil))
+
+		c.Add(1, 1)
+		sleep()
+
+		v, err := c.GetOrAdd(1, newAddFunc(2, nil))
+		assert.NoError(t, err)
+		assert.Equal(t, 2, v)
+
+		v, ok := c.Get(1)
+		assert.True(t, ok)
+		assert.Equal(t, 2, v)
+	})
+
+	t.Run(`return error after max staleness`, func(t *testing.T) {
+		myErr := errors.New(`myErr`)
+		c := cache.TLFU(3, cacheDuration, cache.WithStaleIfError[int, int](cacheDuration, nil))
+
+		c.Add(1, 1)
+		sleep()
+		sleep()
+
+		_, err := c.GetOrAdd(1, newAddFunc(0, myErr))
+		assert.ErrorIs(t, myErr, err)
+	})
+}
+
+func TestRefreshAhead(t *testing.T) {
+	t.Run(`reload hot key before it expires`, func(t *testing.T) {
+		const maxAge = 200 * time.Millisecond
+		c := cache.TLRU(3, maxAge, cache.WithRefreshAhead[int, int](0.5))
+
+		var count atomic.Int32
+		reloaded := make(chan struct{})
+		addFunc := func() (int, error) {
+			n := count.Add(1)
+			if n == 2 {
+				close(reloaded)
+			}
+			return int(n), nil
+		}
+
+		start := time.Now()
+		v, err := c.GetOrAdd(1, addFunc)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, v)
+
+		time.Sleep(maxAge * 3 / 4)
+		v, ok := c.Get(1)
+		assert.True(t, ok)
+		assert.Equal(t, 1, v)
+
+		select {
+		case <-reloaded:
+		case <-time.After(maxAge):
+			t.Fatal(`hot key was not reloaded`)
+		}
+
+		// well after the first value expired, but before the reloaded one does
+		time.Sleep(maxAge*5/4 - time.Since(start))
+		v, ok = c.Get(1)
+		assert.True(t, ok, `refreshed value did not expire`)
+		assert.Equal(t, 2, v)
+	})
+
+	t.Run(`reload values loaded in a batch`, func(t *testing.T) {
+		const maxAge = 200 * time.Millisecond
+		c := cache.TFIFO(3, maxAge, cache.WithRefreshAhead[int, int](0.5))
+
+		reloaded := make(chan []int, 1)
+		_, err := c.GetOrAddMany([]int{1, 2}, func(missing []int) (map[int]int, error) {
+			if len(missing) == 1 {
+				reloaded <- missing
+			}
+
+			values := make(map[int]int)
+			for _, key := range missing {
+				values[key] = key
+			}
+			return values, nil
+		})
+		assert.NoError(t, err)
+
+		time.Sleep(maxAge * 3 / 4)
+		v, ok := c.Get(1)
+		assert.True(t, ok)
+		assert.Equal(t, 1, v)
+
+		select {
+		case keys := <-reloaded:
+			assert.Equal(t, 1, keys[0])
+		case <-time.After(maxAge):
+			t.Fatal(`batch value was not reloaded`)
+		}
+	})
+
+	t.Run(`do not reload early accesses`, func(t *testing.T) {
+		c := cache.TFIFO(3, time.Hour, cache.WithRefreshAhead[int, int](0.2))
+
+		_, err := c.GetOrAdd(1, newAddFunc(1, nil))
+		assert.NoError(t, err)
+
+		addFunc, called := calledAddFunc(2, nil)
+		for i := 0; i < 10; i++ {
+			v, err := c.GetOrAdd(1, addFunc)
+			assert.NoError(t, err)
+			assert.Equal(t, 1, v)
+		}
+		assert.False(t, *called)
+	})
+
+	t.Run(`do not reload added values`, func(t *testing.T) {
+		c := cache.TLFU(3, cacheDuration, cache.WithRefreshAhead[int, int](1))
+
+		c.Add(1, 1)
+		_, ok := c.Get(1)
+		assert.True(t, ok)
+
+		sleep()
+		_, ok = c.Get(1)
+		assert.False(t, ok)
+	})
+}
+
+func TestEarlyExpiration(t *testing.T) {
+	t.Run(`reload slow values early`, func(t *testing.T) {
+		c := cache.TLRU(3, cacheDuration, cache.WithEarlyExpiration[int, int](100))
+
+		var count atomic.Int32
+		addFunc := func() (int, error) {
+			time.Sleep(time.Millisecond)
+			return int(count.Add(1)), nil
+		}
+
+		_, err := c.GetOrAdd(1, addFunc)
+		assert.NoError(t, err)
+
+		for i := 0; i < 100; i++ {
+			_, err = c.GetOrAdd(1, addFunc)
+			assert.NoError(t, err)
+		}
+
+		// the early reload runs in the background
+		deadline := time.Now().Add(time.Second)
+		for count.Load() <= 1 && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		assert.True(t, count.Load() > 1, `value was not reloaded early`)
+	})
+
+	t.Run(`do not reload fast values early`, func(t *testing.T) {
+		c := cache.TFIFO(3, time.Hour, cache.WithEarlyExpiration[int, int](1))
+
+		_, err := c.GetOrAdd(1, newAddFunc(1, nil))
+		assert.NoError(t, err)
+
+		addFunc, called := calledAddFunc(2, nil)
+		for i := 0; i < 100; i++ {
+			v, ok := c.Get(1)
+			assert.True(t, ok)
+			assert.Equal(t, 1, v)
+
+			_, err = c.GetOrAdd(1, addFunc)
+			assert.NoError(t, err)
+		}
+		assert.False(t, *called)
+	})
+}
+
+func TestErrorCaching(t *testing.T) {
+	errNotFound := errors.New(`not found`)
+	errTimeout := errors.New(`timeout`)
+	cacheable := func(err error) bool {
+		return errors.Is(err, errNotFound)
+	}
+
+	t.Run(`return cached error`, func(t *testing.T) {
+		c := cache.TLRU(3, time.Hour, cache.WithErrorCaching[int, int](time.Hour, cacheable))
+
+		_, err := c.GetOrAdd(1, newAddFunc(0, errNotFound))
+		assert.ErrorIs(t, errNotFound, err)
+
+		addFunc, called := calledAddFunc(1, nil)
+		_, err = c.GetOrAdd(1, addFunc)
+		assert.ErrorIs(t, errNotFound, err)
+		assert.False(t, *called)
+
+		_, ok := c.Get(1)
+		assert.False(t, ok, `Get does not return cached errors`)
+
+		s := c.Stats()
+		assert.Equal(t, uint64(0), s.Hits)
+		assert.Equal(t, uint64(3), s.Misses, `cached errors are misses`)
+		assert.Equal(t, uint64(0), s.Loads)
+		assert.Equal(t, uint64(1), s.LoadErrors)
+		assert.Equal(t, 0, s.Entries, `cached errors are not entries, like in Len`)
+	})
+
+	t.Run(`do not report evicted errors`, func(t *testing.T) {
+		evictions, onEvict := recordEvictions()
+		c := cache.TLRU(1, time.Hour, cache.WithErrorCaching[int, int](time.Hour, nil), onEvict)
+
+		_, err := c.GetOrAdd(1, newAddFunc(0, errNotFound))
+		assert.ErrorIs(t, errNotFound, err)
+
+		c.Add(2, 2)
+		assert.Equal(t, 0, len(*evictions))
+		assert.Equal(t, uint64(0), c.Stats().Evictions[cache.ReasonCapacity])
+
+		c.Add(3, 3)
+		assert.Equal(t, 1, len(*evictions))
+		assert.Equal(t, evicted{2, 2, cache.ReasonCapacity}, (*evictions)[0])
+	})
+
+	t.Run(`do not cache other errors`, func(t *testing.T) {
+		c := cache.TFIFO(3, time.Hour, cache.WithErrorCaching[int, int](time.Hour, cacheable))
+
+		_, err := c.GetOrAdd(1, newAddFunc(0, errTimeout))
+		assert.ErrorIs(t, errTimeout, err)
+
+		v, err := c.GetOrAdd(1, newAddFunc(1, nil))
+		assert.NoError(t, err)
+		assert.Equal(t, 1, v)
+	})
+
+	t.Run(`load again after error expires`, func(t *testing.T) {
+		c := cache.TLFU(3, time.Hour, cache.WithErrorCaching[int, int](cacheDuration, nil))
+
+		_, err := c.GetOrAdd(1, newAddFunc(0, errTimeout))
+		assert.ErrorIs(t, errTimeout, err)
+
+		sleep()
+
+		v, err := c.GetOrAdd(1, newAddFunc(1, nil))
+		assert.NoError(t, err)
+		assert.Equal(t, 1, v)
+
+		sleep()
+
+		v, ok := c.Get(1)
+		assert.True(t, ok, `values keep the normal TTL`)
+		assert.Equal(t, 1, v)
+	})
+
+	t.Run(`do not replace stale value with error`, func(t *testing.T) {
+		c := cache.TLRU(3, cacheDuration,
+			cache.WithErrorCaching[int, int](time.Hour, nil),
+			cache.WithStaleIfError[int, int](time.Hour, nil),
+		)
+
+		c.Add(1, 1)
+		sleep()
+
+		v, err := c.GetOrAdd(1, newAddFunc(0, errNotFound))
+		assert.NoError(t, err)
+		assert.Equal(t, 1, v)
+	})
+}
@@ -0,0 +1,217 @@
+package cache
+
+import "github.com/FallenTaters/cache/list"
+
+type segment int
+
+const (
+	windowSegment segment = iota
+	probationSegment
+	protectedSegment
+)
+
+type tinyLFUEntry[K comparable, V any] struct {
+	keyValue[K, V]
+
+	segment segment
+}
+
+// tinyLFU is W-TinyLFU: new entries enter a small window LRU. Entries leaving the window
+// only replace the victim of the segmented main LRU if the sketch estimates they are used more often.
+type tinyLFU[K comparable, V any] struct {
+	evictor[K, V]
+
+	maxWindow    int
+	maxMain      int
+	maxProtected int
+
+	window    *list.List[tinyLFUEntry[K, V]]
+	probation *list.List[tinyLFUEntry[K, V]]
+	protected *list.List[tinyLFUEntry[K, V]]
+	values    map[K]*list.Element[tinyLFUEntry[K, V]]
+
+	sketch *sketch[K]
+}
+
+// TinyLFU uses 1% of maxEntries as a window LRU and the rest as a segmented LRU,
+// with 80% of it protected for entries that were used more than once.
+func TinyLFU[K comparable, V any](maxEntries int, opts ...Option[K, V]) Cache[K, V] {
+	t := &tinyLFU[K, V]{
+		window:    list.New[tinyLFUEntry[K, V]](),
+		probation: list.New[tinyLFUEntry[K, V]](),
+		protected: list.New[tinyLFUEntry[K, V]](),
+		values:    make(map[K]*list.Element[tinyLFUEntry[K, V]], maxEntries),
+		sketch:    newSketch[K](maxEntries),
+	}
+	t.setSize(maxEntries)
+
+	return newBase[K, V](t, opts)
+}
+
+// setSize sets the sizes of the segments for maxEntries
+func (t *tinyLFU[K, V]) setSize(maxEntries int) {
+	t.maxWindow = maxEntries / 100
+	if t.maxWindow < 1 {
+		t.maxWindow = min(1, maxEntries)
+	}
+	t.maxMain = maxEntries - t.maxWindow
+	t.maxProtected = t.maxMain * 8 / 10
+}
+
+func (t *tinyLFU[K, V]) get(key K) (V, bool) {
+	t.sketch.increment(key)
+
+	e, ok := t.values[key]
+	if !ok {
+		var empty V
+		return empty, false
+	}
+
+	t.touch(e)
+
+	return e.Value.value, true
+}
+
+func (t *tinyLFU[K, V]) peek(key K) (V, bool) {
+	e, ok := t.values[key]
+	if !ok {
+		var empty V
+		return empty, false
+	}
+
+	return e.Value.value, true
+}
+
+func (t *tinyLFU[K, V]) readOnlyGet() bool {
+	return false
+}
+
+func (t *tinyLFU[K, V]) add(key K, value V) {
+	t.sketch.increment(key)
+
+	if e, ok := t.values[key]; ok {
+		t.evicted(key, e.Value.value, ReasonReplaced)
+		e.Value.value = value
+		t.touch(e)
+		return
+	}
+
+	t.values[key] = t.window.PushFront(tinyLFUEntry[K, V]{
+		keyValue: keyValue[K, V]{key: key, value: value},
+		segment:  windowSegment,
+	})
+
+	if t.window.Len() <= t.maxWindow {
+		return
+	}
+
+	// the window is full, its last entry becomes a candidate for the main cache
+	candidate := t.move(t.window.Back(), probationSegment)
+	if t.probation.Len()+t.protected.Len() <= t.maxMain {
+		return
+	}
+
+	victim := t.probation.Back()
+	if victim == candidate {
+		victim = t.protected.Back()
+	}
+
+	evict := candidate.Value.key
+	if victim != nil && t.sketch.estimate(candidate.Value.key) > t.sketch.estimate(victim.Value.key) {
+		evict = victim.Value.key
+	}
+
+	v, _ := t.remove(evict)
+	t.evicted(evict, v, ReasonCapacity)
+}
+
+func (t *tinyLFU[K, V]) remove(key K) (V, bool) {
+	e, ok := t.values[key]
+	if !ok {
+		var empty V
+		return empty, false
+	}
+
+	t.segment(e.Value.segment).Remove(e)
+	delete(t.values, key)
+
+	return e.Value.value, true
+}
+
+// resize keeps the sketch, so the frequencies it has learned are not lost
+func (t *tinyLFU[K, V]) resize(maxEntries int) {
+	t.setSize(maxEntries)
+
+	for t.window.Len() > t.maxWindow {
+		t.move(t.window.Back(), probationSegment)
+	}
+
+	for t.probation.Len()+t.protected.Len() > t.maxMain {
+		victim := t.probation.Back()
+		if victim == nil {
+			victim = t.protected.Back()
+		}
+
+		key := victim.Value.key
+		v, _ := t.remove(key)
+		t.evicted(key, v, ReasonCapacity)
+	}
+
+	for t.protected.Len() > t.maxProtected {
+		t.move(t.protected.Back(), probationSegment)
+	}
+}
+
+func (t *tinyLFU[K, V]) walk(fn func(K, V)) {
+	for _, l := range []*list.List[tinyLFUEntry[K, V]]{t.probation, t.protected, t.window} {
+		for e := l.Back(); e != nil; e = e.Prev() {
+			fn(e.Value.key, e.Value.value)
+		}
+	}
+}
+
+func (t *tinyLFU[K, V]) len() int {
+	return len(t.values)
+}
+
+func (t *tinyLFU[K, V]) name() string {
+	return `tinylfu`
+}
+
+// touch records a hit on e: entries in probation are promoted to protected,
+// demoting the least recently used protected entry if needed
+func (t *tinyLFU[K, V]) touch(e *list.Element[tinyLFUEntry[K, V]]) {
+	switch e.Value.segment {
+	case windowSegment:
+		t.window.MoveToFront(e)
+	case protectedSegment:
+		t.protected.MoveToFront(e)
+	case probationSegment:
+		t.move(e, protectedSegment)
+		if t.protected.Len() > t.maxProtected {
+			t.move(t.protected.Back(), probationSegment)
+		}
+	}
+}
+
+// move moves e to the front of segment s and returns its new element
+func (t *tinyLFU[K, V]) move(e *list.Element[tinyLFUEntry[K, V]], s segment) *list.Element[tinyLFUEntry[K, V]] {
+	entry := t.segment(e.Value.segment).Remove(e)
+	entry.segment = s
+
+	moved := t.segment(s).PushFront(entry)
+	t.values[entry.key] = moved
+
+	return moved
+}
+
+func (t *tinyLFU[K, V]) segment(s segment) *list.List[tinyLFUEntry[K, V]] {
+	switch s {
+	case windowSegment:
+		return t.window
+	case probationSegment:
+		return t.probation
+	default:
+		return t.protected
+	}
+}
@@ -0,0 +1,143 @@
+package cache_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/FallenTaters/cache"
+	"github.com/FallenTaters/cache/assert"
+)
+
+func newTinyLFU() cache.Cache[int, int] {
+	return cache.TinyLFU[int, int](100)
+}
+
+func TestTinyLFU(t *testing.T) {
+	t.Run(`get non-existing`, func(t *testing.T) {
+		c := newTinyLFU()
+
+		v, ok := c.Get(1)
+		assert.False(t, ok)
+		assert.Equal(t, 0, v)
+	})
+
+	t.Run(`add and get`, func(t *testing.T) {
+		c := newTinyLFU()
+
+		c.Add(1, 1)
+
+		v, ok := c.Get(1)
+		assert.True(t, ok)
+		assert.Equal(t, 1, v)
+	})
+
+	t.Run(`limit number of entries`, func(t *testing.T) {
+		c := cache.TinyLFU[int, int](2)
+
+		c.Add(1, 1)
+		c.Add(2, 2)
+		c.Add(3, 3)
+
+		var found int
+		for i := 1; i <= 3; i++ {
+			if _, ok := c.Get(i); ok {
+				found++
+			}
+		}
+		assert.Equal(t, 2, found)
+	})
+
+	t.Run(`popular entries survive a scan`, func(t *testing.T) {
+		c := newTinyLFU()
+
+		for i := 0; i < 10; i++ {
+			for key := 0; key < 50; key++ {
+				_ = c.MustGetOrAdd(key, newAddFunc(key, nil))
+			}
+		}
+
+		for key := 1000; key < 2000; key++ {
+			_ = c.MustGetOrAdd(key, newAddFunc(key, nil))
+		}
+
+		var found int
+		for key := 0; key < 50; key++ {
+			if _, ok := c.Get(key); ok {
+				found++
+			}
+		}
+		assert.True(t, found >= 45, `most popular entries should be kept`)
+	})
+
+	t.Run(`return error`, func(t *testing.T) {
+		myErr := errors.New(`myErr`)
+		c := newTinyLFU()
+
+		_, err := c.GetOrAdd(1, newAddFunc(0, myErr))
+		assert.ErrorIs(t, myErr, err)
+	})
+
+	t.Run(`only call addFunc when necessary`, func(t *testing.T) {
+		c := newTinyLFU()
+
+		addFunc, called := calledAddFunc(1, nil)
+
+		c.MustGetOrAdd(1, addFunc)
+		assert.True(t, *called)
+
+		*called = false
+		c.MustGetOrAdd(1, addFunc)
+		assert.False(t, *called)
+	})
+
+	t.Run(`delete`, func(t *testing.T) {
+		c := newTinyLFU()
+
+		for key := 0; key < 10; key++ {
+			c.Add(key, key)
+			_, _ = c.Get(key)
+		}
+
+		for key := 0; key < 10; key++ {
+			c.Delete(key)
+
+			v, ok := c.Get(key)
+			assert.False(t, ok)
+			assert.Equal(t, 0, v)
+		}
+	})
+
+	t.Run(`concurrent operations`, func(t *testing.T) {
+		c := newTinyLFU()
+
+		var wg sync.WaitGroup
+
+		wg.Add(concurrentCount)
+		go func() {
+			for i := 0; i < concurrentCount; i++ {
+				expected := i
+				go func() {
+					actual, err := c.GetOrAdd(expected, newAddFunc(expected, nil))
+					assert.Equal(t, expected, actual)
+					assert.NoError(t, err)
+
+					wg.Done()
+				}()
+			}
+		}()
+
+		wg.Add(concurrentCount)
+		go func() {
+			for i := concurrentCount - 1; i >= 0; i-- {
+				expected := i
+				go func() {
+					c.Delete(expected)
+					wg.Done()
+				}()
+			}
+		}()
+
+		wg.Wait()
+	})
+}
@@ -0,0 +1,418 @@
+package cache
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// WALCache is a Cache that writes its changes to a write-ahead log, see OpenWAL
+type WALCache[K comparable, V any] interface {
+	Cache[K, V]
+
+	// Compact writes all entries to a snapshot and empties the log
+	Compact() error
+
+	// Close stops compaction and closes the log.
+	// It returns the first error that occurred while writing the log
+	Close() error
+}
+
+const (
+	walFileName      = `wal`
+	snapshotFileName = `snapshot`
+
+	// walHeaderSize is the size of the length and the CRC-32 checksum in front of every record
+	walHeaderSize = 8
+)
+
+type walOp uint8
+
+const (
+	walPut walOp = iota
+	walDelete
+	walClear
+)
+
+// walRecord is a change written to the log. Put records hold the entry as it is after the change,
+// so replaying a record more than once does not change the result
+type walRecord[K comparable, V any] struct {
+	Op    walOp
+	Entry entry[K, V]
+}
+
+// watcher is implemented by the caches of this package, so the log also gets changes that were not made
+// through the wal, such as evictions and background reloads
+type watcher[K comparable] interface {
+	// watch makes the cache call onChange after every change to the entry of a key.
+	// It returns false if not all changes can be watched
+	watch(onChange func(K)) bool
+}
+
+type wal[K comparable, V any] struct {
+	Cache[K, V]
+
+	dir     string
+	codec   Codec
+	janitor *janitor
+
+	// watched is true if the wrapped cache reports all changes, otherwise the methods of wal log their own changes
+	watched bool
+
+	// mu guards file and err, and keeps Compact from running while a record is written
+	mu   sync.Mutex
+	file *os.File
+	err  error
+}
+
+// OpenWAL wraps c so every Add, Delete and other change is appended to a log in dir.
+// The log and the snapshot in dir are replayed into c first, so c should be empty and have the same size as before.
+// Time-aware caches keep the time at which entries were added, so entries that expired in the meantime are not restored.
+// For the caches of this package, evictions and values reloaded in the background are logged as well.
+// Other caches only log the changes made through the returned WALCache.
+// Gets are not logged, so the order of recency or frequency used for evictions is only restored as of the last Compact.
+// Records are written with the Codec set with WithCodec and a CRC-32 checksum. A record that was only partly written
+// before a crash is dropped. Pass WithCompaction to compact the log in the background, or call Compact.
+// The log is not synced to disk after every record, so it survives a crash of the process but not of the machine.
+func OpenWAL[K comparable, V any](dir string, c Cache[K, V], opts ...Option[K, V]) (WALCache[K, V], error) {
+	o := newOptions(opts)
+
+	w := &wal[K, V]{
+		Cache: c,
+		dir:   dir,
+		codec: o.codec,
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	if err := w.loadSnapshot(); err != nil {
+		return nil, err
+	}
+
+	size, err := w.replay()
+	if err != nil {
+		return nil, err
+	}
+
+	w.file, err = os.OpenFile(w.path(walFileName), os.O_WRONLY|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	// drop a record that was only partly written
+	if err := w.file.Truncate(size); err != nil {
+		w.file.Close()
+		return nil, err
+	}
+	if _, err := w.file.Seek(size, io.SeekStart); err != nil {
+		w.file.Close()
+		return nil, err
+	}
+
+	if c, ok := c.(watcher[K]); ok {
+		w.watched = c.watch(func(key K) {
+			w.log(key)
+		})
+	}
+
+	if o.compactInterval > 0 {
+		w.janitor = startJanitor(o.compactInterval, func() {
+			if err := w.Compact(); err != nil {
+				w.mu.Lock()
+				w.fail(err)
+				w.mu.Unlock()
+			}
+		})
+	}
+
+	return w, nil
+}
+
+func (w *wal[K, V]) Add(key K, value V) {
+	w.Cache.Add(key, value)
+	w.track(key)
+}
+
+func (w *wal[K, V]) AddMany(values map[K]V) {
+	w.Cache.AddMany(values)
+	w.track(slices.Collect(maps.Keys(values))...)
+}
+
+func (w *wal[K, V]) GetOrAdd(key K, addFunc AddFunc[V]) (V, error) {
+	return w.GetOrAddCtx(context.Background(), key, addFunc.withContext())
+}
+
+func (w *wal[K, V]) GetOrAddCtx(ctx context.Context, key K, addFunc AddFuncCtx[V]) (V, error) {
+	var loaded atomic.Bool
+	v, err := w.Cache.GetOrAddCtx(ctx, key, func(ctx context.Context) (V, error) {
+		loaded.Store(true)
+		return addFunc(ctx)
+	})
+	if err == nil && loaded.Load() {
+		w.track(key)
+	}
+
+	return v, err
+}
+
+func (w *wal[K, V]) MustGetOrAdd(key K, addFunc AddFunc[V]) V {
+	v, err := w.GetOrAdd(key, addFunc)
+	if err != nil {
+		panic(err)
+	}
+
+	return v
+}
+
+func (w *wal[K, V]) GetOrAddMany(keys []K, addFunc BatchAddFunc[K, V]) (map[K]V, error) {
+	var mu sync.Mutex
+	var loaded []K
+	values, err := w.Cache.GetOrAddMany(keys, func(missing []K) (map[K]V, error) {
+		mu.Lock()
+		loaded = append(loaded, missing...)
+		mu.Unlock()
+
+		return addFunc(missing)
+	})
+
+	mu.Lock()
+	w.track(loaded...)
+	mu.Unlock()
+
+	return values, err
+}
+
+func (w *wal[K, V]) Delete(key K) {
+	w.Cache.Delete(key)
+	w.track(key)
+}
+
+func (w *wal[K, V]) DeleteMany(keys []K) {
+	w.Cache.DeleteMany(keys)
+	w.track(keys...)
+}
+
+func (w *wal[K, V]) Compute(key K, fn ComputeFunc[V]) (V, bool) {
+	v, ok := w.Cache.Compute(key, fn)
+	w.track(key)
+
+	return v, ok
+}
+
+func (w *wal[K, V]) AddIfAbsent(key K, value V) bool {
+	added := w.Cache.AddIfAbsent(key, value)
+	if added {
+		w.track(key)
+	}
+
+	return added
+}
+
+func (w *wal[K, V]) Clear() {
+	if w.watched {
+		w.Cache.Clear()
+		return
+	}
+
+	// the lock keeps records of entries added after clearing from being written before the clear record
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.Cache.Clear()
+	w.append(walRecord[K, V]{Op: walClear})
+}
+
+// Load compacts the log after loading, so the loaded entries are in the snapshot
+func (w *wal[K, V]) Load(r io.Reader) error {
+	if err := w.Cache.Load(r); err != nil {
+		return err
+	}
+
+	return w.Compact()
+}
+
+func (w *wal[K, V]) Compact() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return os.ErrClosed
+	}
+
+	tmp := w.path(snapshotFileName + `.tmp`)
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	err = encodeEntries(w.codec, f, entriesOf(w.Cache))
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	// if the process crashes before the log is emptied, replaying it on top of the snapshot has the same result
+	if err := os.Rename(tmp, w.path(snapshotFileName)); err != nil {
+		return err
+	}
+
+	if err := w.file.Truncate(0); err != nil {
+		return err
+	}
+	_, err = w.file.Seek(0, io.SeekStart)
+
+	return err
+}
+
+func (w *wal[K, V]) Close() error {
+	w.janitor.Close()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return w.err
+	}
+
+	err := w.file.Close()
+	w.file = nil
+
+	return cmp.Or(w.err, err)
+}
+
+// track logs keys that were changed through w, unless the wrapped cache reports its changes itself
+func (w *wal[K, V]) track(keys ...K) {
+	if !w.watched {
+		w.log(keys...)
+	}
+}
+
+// log writes the current state of every key, whether it was added, replaced or removed
+func (w *wal[K, V]) log(keys ...K) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// background reloads can still change the cache after Close
+	if w.file == nil {
+		return
+	}
+
+	for _, key := range keys {
+		if e, ok := peekEntryOf(w.Cache, key); ok {
+			w.append(walRecord[K, V]{Op: walPut, Entry: e})
+		} else {
+			w.append(walRecord[K, V]{Op: walDelete, Entry: entry[K, V]{Key: key}})
+		}
+	}
+}
+
+// append writes record with its length and checksum in front of it, w.mu must be locked
+func (w *wal[K, V]) append(record walRecord[K, V]) {
+	var buf bytes.Buffer
+	buf.Write(make([]byte, walHeaderSize))
+
+	// every record gets its own encoder, so it can be decoded on its own
+	if err := w.codec.NewEncoder(&buf).Encode(record); err != nil {
+		w.fail(err)
+		return
+	}
+
+	frame := buf.Bytes()
+	payload := frame[walHeaderSize:]
+	binary.LittleEndian.PutUint32(frame[0:], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(frame[4:], crc32.ChecksumIEEE(payload))
+
+	_, err := w.file.Write(frame)
+	w.fail(err)
+}
+
+// fail keeps the first error, so Close can return it, w.mu must be locked
+func (w *wal[K, V]) fail(err error) {
+	if w.err == nil {
+		w.err = err
+	}
+}
+
+func (w *wal[K, V]) loadSnapshot() error {
+	f, err := os.Open(w.path(snapshotFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	entries, err := decodeEntries[K, V](w.codec, f)
+	if err != nil {
+		return err
+	}
+
+	restoreTo(w.Cache, entries)
+	return nil
+}
+
+// replay applies all complete records in the log and returns the size of the log up to the last of them
+func (w *wal[K, V]) replay() (int64, error) {
+	data, err := os.ReadFile(w.path(walFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	offset := 0
+	for len(data)-offset >= walHeaderSize {
+		size := int(binary.LittleEndian.Uint32(data[offset:]))
+		sum := binary.LittleEndian.Uint32(data[offset+4:])
+
+		start := offset + walHeaderSize
+		if len(data)-start < size || crc32.ChecksumIEEE(data[start:start+size]) != sum {
+			break
+		}
+
+		var record walRecord[K, V]
+		if err := w.codec.NewDecoder(bytes.NewReader(data[start : start+size])).Decode(&record); err != nil {
+			return 0, err
+		}
+		w.apply(record)
+
+		offset = start + size
+	}
+
+	return int64(offset), nil
+}
+
+func (w *wal[K, V]) apply(record walRecord[K, V]) {
+	switch record.Op {
+	case walPut:
+		restoreTo(w.Cache, []entry[K, V]{record.Entry})
+	case walDelete:
+		w.Cache.Delete(record.Entry.Key)
+	case walClear:
+		w.Cache.Clear()
+	}
+}
+
+func (w *wal[K, V]) path(name string) string {
+	return filepath.Join(w.dir, name)
+}
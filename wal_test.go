@@ -0,0 +1,205 @@
+package cache_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/FallenTaters/cache"
+	"github.com/FallenTaters/cache/assert"
+)
+
+func openWAL(t *testing.T, dir string, c cache.Cache[int, int], opts ...cache.Option[int, int]) cache.WALCache[int, int] {
+	t.Helper()
+
+	w, err := cache.OpenWAL(dir, c, opts...)
+	assert.NoError(t, err)
+
+	return w
+}
+
+func TestWAL(t *testing.T) {
+	t.Run(`replay changes`, func(t *testing.T) {
+		dir := t.TempDir()
+		w := openWAL(t, dir, cache.LRU[int, int](10))
+
+		w.Add(1, 1)
+		w.Add(2, 2)
+		w.AddMany(map[int]int{3: 3, 4: 4})
+		w.Delete(2)
+		w.DeleteMany([]int{4})
+		w.Compute(1, increment)
+		_, err := w.GetOrAdd(5, newAddFunc(5, nil))
+		assert.NoError(t, err)
+		_, err = w.GetOrAddMany([]int{6}, func([]int) (map[int]int, error) {
+			return map[int]int{6: 6}, nil
+		})
+		assert.NoError(t, err)
+		assert.True(t, w.AddIfAbsent(7, 7))
+		assert.NoError(t, w.Close())
+
+		w = openWAL(t, dir, cache.LRU[int, int](10))
+		defer w.Close()
+
+		assertKeys(t, []int{3, 1, 5, 6, 7}, w.Keys())
+		v, ok := w.Get(1)
+		assert.True(t, ok)
+		assert.Equal(t, 2, v)
+	})
+
+	t.Run(`replay evictions`, func(t *testing.T) {
+		dir := t.TempDir()
+		w := openWAL(t, dir, cache.LRU[int, int](2))
+		w.Add(1, 1)
+		w.Add(2, 2)
+		w.Get(1)
+		w.Add(3, 3)
+		assertKeys(t, []int{1, 3}, w.Keys())
+		assert.NoError(t, w.Close())
+
+		w = openWAL(t, dir, cache.LRU[int, int](2))
+		defer w.Close()
+		assertKeys(t, []int{1, 3}, w.Keys())
+	})
+
+	t.Run(`replay background reloads`, func(t *testing.T) {
+		const maxAge = 100 * time.Millisecond
+		newCache := func() cache.Cache[int, int] {
+			return cache.TLRU(10, maxAge, cache.WithStaleWhileRevalidate[int, int](time.Hour))
+		}
+
+		dir := t.TempDir()
+		w := openWAL(t, dir, newCache())
+		w.Add(1, 1)
+		time.Sleep(maxAge)
+
+		path := filepath.Join(dir, `wal`)
+		info, err := os.Stat(path)
+		assert.NoError(t, err)
+		size := info.Size()
+
+		v, err := w.GetOrAdd(1, newAddFunc(2, nil))
+		assert.NoError(t, err)
+		assert.Equal(t, 1, v)
+
+		// the reloaded value is logged in the background
+		deadline := time.Now().Add(maxAge)
+		for info.Size() == size && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+			info, err = os.Stat(path)
+			assert.NoError(t, err)
+		}
+		assert.NoError(t, w.Close())
+
+		w = openWAL(t, dir, newCache())
+		defer w.Close()
+		v, ok := w.Get(1)
+		assert.True(t, ok)
+		assert.Equal(t, 2, v)
+	})
+
+	t.Run(`drop partly written record`, func(t *testing.T) {
+		dir := t.TempDir()
+		w := openWAL(t, dir, cache.FIFO[int, int](10))
+		w.Add(1, 1)
+		w.Add(2, 2)
+		assert.NoError(t, w.Close())
+
+		path := filepath.Join(dir, `wal`)
+		data, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.NoError(t, os.WriteFile(path, data[:len(data)-3], 0o644))
+
+		w = openWAL(t, dir, cache.FIFO[int, int](10))
+		assertKeys(t, []int{1}, w.Keys())
+
+		w.Add(3, 3)
+		assert.NoError(t, w.Close())
+
+		w = openWAL(t, dir, cache.FIFO[int, int](10))
+		defer w.Close()
+		assertKeys(t, []int{1, 3}, w.Keys())
+	})
+
+	t.Run(`stop at checksum mismatch`, func(t *testing.T) {
+		dir := t.TempDir()
+		w := openWAL(t, dir, cache.FIFO[int, int](10))
+		w.Add(1, 1)
+		w.Add(2, 2)
+		assert.NoError(t, w.Close())
+
+		path := filepath.Join(dir, `wal`)
+		data, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		data[len(data)-1] ^= 0xff
+		assert.NoError(t, os.WriteFile(path, data, 0o644))
+
+		w = openWAL(t, dir, cache.FIFO[int, int](10))
+		defer w.Close()
+		assertKeys(t, []int{1}, w.Keys())
+	})
+
+	t.Run(`compact`, func(t *testing.T) {
+		dir := t.TempDir()
+		w := openWAL(t, dir, cache.LRU[int, int](10))
+		w.Add(1, 1)
+		w.Add(2, 2)
+		w.Get(1)
+		assert.NoError(t, w.Compact())
+
+		info, err := os.Stat(filepath.Join(dir, `wal`))
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), info.Size())
+
+		w.Add(3, 3)
+		assert.NoError(t, w.Close())
+
+		w = openWAL(t, dir, cache.LRU[int, int](10))
+		defer w.Close()
+		assertKeys(t, []int{2, 1, 3}, w.Keys())
+	})
+
+	t.Run(`clear`, func(t *testing.T) {
+		dir := t.TempDir()
+		w := openWAL(t, dir, cache.LRU[int, int](10))
+		w.Add(1, 1)
+		w.Clear()
+		w.Add(2, 2)
+		assert.NoError(t, w.Close())
+
+		w = openWAL(t, dir, cache.LRU[int, int](10))
+		defer w.Close()
+		assertKeys(t, []int{2}, w.Keys())
+	})
+
+	t.Run(`keep expiry`, func(t *testing.T) {
+		dir := t.TempDir()
+		w := openWAL(t, dir, cache.TLRU[int, int](10, cacheDuration))
+		w.Add(1, 1)
+		assert.NoError(t, w.Compact())
+		w.Add(2, 2)
+		assert.NoError(t, w.Close())
+
+		sleep()
+
+		w = openWAL(t, dir, cache.TLRU[int, int](10, cacheDuration))
+		defer w.Close()
+		assert.Equal(t, 0, w.Len())
+	})
+
+	t.Run(`compact in background`, func(t *testing.T) {
+		dir := t.TempDir()
+		w := openWAL(t, dir, cache.LRU[int, int](10), cache.WithCompaction[int, int](time.Millisecond))
+		w.Add(1, 1)
+		sleep()
+		assert.NoError(t, w.Close())
+
+		_, err := os.Stat(filepath.Join(dir, `snapshot`))
+		assert.NoError(t, err)
+
+		w = openWAL(t, dir, cache.LRU[int, int](10))
+		defer w.Close()
+		assertKeys(t, []int{1}, w.Keys())
+	})
+}